@@ -0,0 +1,234 @@
+// Package admin provides an http.Handler for inspecting and changing
+// logging levels at runtime.
+//
+// The handler exposes the following endpoints, which all accept and
+// produce JSON documents of the form {"name": <name>, "level": <level>}:
+//
+//	GET  /level             the threshold of the standard logger
+//	PUT  /level
+//	GET  /loggers           all named loggers (see grip.Named)
+//	GET  /loggers/{name}
+//	PUT  /loggers/{name}    sets a level rule (see grip.SetLevelRule)
+//	DELETE /loggers/{name}  removes a level rule
+//	GET  /senders           all senders registered with the handler
+//	GET  /senders/{name}
+//	PUT  /senders/{name}
+//
+// PUT /level sets both the priority of the standard logger's sender
+// and the threshold of the standard logger, which level rules may
+// lower the sender below (see grip.SetLevelRule.)
+//
+// The name of the /loggers/{name} endpoints may be a level rule
+// pattern (e.g. "db.*"): PUT changes the rule and responds with the
+// effective threshold that the rule gives the loggers it matches,
+// without registering named loggers for patterns, and DELETE
+// responds with the level of the removed rule. Levels are parsed with
+// level.FromString, and requests that specify an invalid level or
+// pattern are rejected. Mount the handler with
+// http.StripPrefix to serve it below a path prefix.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/tychoish/grip"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/send"
+)
+
+// Level is the document produced and accepted by the handler.
+type Level struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// Handler is an http.Handler that exposes logging levels. Use
+// NewHandler to construct a Handler.
+type Handler struct {
+	mux     *http.ServeMux
+	mtx     sync.RWMutex
+	senders map[string]send.Sender
+}
+
+// NewHandler constructs a Handler that exposes the standard logger
+// and named loggers. Use RegisterSender to expose additional senders.
+func NewHandler() *Handler {
+	h := &Handler{
+		mux:     http.NewServeMux(),
+		senders: map[string]send.Sender{},
+	}
+
+	h.mux.HandleFunc("GET /level", h.getStandard)
+	h.mux.HandleFunc("PUT /level", h.putStandard)
+	h.mux.HandleFunc("GET /loggers", h.listLoggers)
+	h.mux.HandleFunc("GET /loggers/{name}", h.getLogger)
+	h.mux.HandleFunc("PUT /loggers/{name}", h.putLogger)
+	h.mux.HandleFunc("DELETE /loggers/{name}", h.deleteLogger)
+	h.mux.HandleFunc("GET /senders", h.listSenders)
+	h.mux.HandleFunc("GET /senders/{name}", h.getSender)
+	h.mux.HandleFunc("PUT /senders/{name}", h.putSender)
+
+	return h
+}
+
+// RegisterSender exposes the sender by name, replacing any sender
+// previously registered with the same name.
+func (h *Handler) RegisterSender(name string, s send.Sender) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.senders[name] = s
+}
+
+// UnregisterSender removes the sender from the handler.
+func (h *Handler) UnregisterSender(name string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	delete(h.senders, name)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) { h.mux.ServeHTTP(w, r) }
+
+func (h *Handler) getStandard(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Level{Name: grip.Sender().Name(), Level: grip.Named("").Threshold().String()})
+}
+
+func (h *Handler) putStandard(w http.ResponseWriter, r *http.Request) {
+	p, ok := readLevel(w, r)
+	if !ok {
+		return
+	}
+	grip.Sender().SetPriority(p)
+	grip.Named("").SetThreshold(p)
+	h.getStandard(w, r)
+}
+
+func (h *Handler) listLoggers(w http.ResponseWriter, _ *http.Request) {
+	out := []Level{}
+	for name, logger := range grip.NamedLoggers() {
+		out = append(out, Level{Name: name, Level: logger.Threshold().String()})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) getLogger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	for n, logger := range grip.NamedLoggers() {
+		if n == name {
+			writeJSON(w, http.StatusOK, Level{Name: name, Level: logger.Threshold().String()})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("logger %q is not registered", name))
+}
+
+func (h *Handler) putLogger(w http.ResponseWriter, r *http.Request) {
+	p, ok := readLevel(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	if err := grip.SetLevelRule(name, p); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	effective := max(p, grip.Sender().Priority())
+	for n, logger := range grip.NamedLoggers() {
+		if n == name {
+			effective = logger.Threshold()
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, Level{Name: name, Level: effective.String()})
+}
+
+func (h *Handler) deleteLogger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, ok := grip.LevelRules()[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("level rule %q is not set", name))
+		return
+	}
+	if err := grip.SetLevelRule(name, level.Invalid); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Level{Name: name, Level: p.String()})
+}
+
+func (h *Handler) listSenders(w http.ResponseWriter, _ *http.Request) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	out := make([]Level, 0, len(h.senders))
+	for _, name := range slices.Sorted(maps.Keys(h.senders)) {
+		out = append(out, Level{Name: name, Level: h.senders[name].Priority().String()})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) getSender(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s, ok := h.sender(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("sender %q is not registered", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, Level{Name: name, Level: s.Priority().String()})
+}
+
+func (h *Handler) putSender(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s, ok := h.sender(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("sender %q is not registered", name))
+		return
+	}
+
+	p, ok := readLevel(w, r)
+	if !ok {
+		return
+	}
+	s.SetPriority(p)
+	writeJSON(w, http.StatusOK, Level{Name: name, Level: s.Priority().String()})
+}
+
+func (h *Handler) sender(name string) (send.Sender, bool) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	s, ok := h.senders[name]
+	return s, ok
+}
+
+func readLevel(w http.ResponseWriter, r *http.Request) (level.Priority, bool) {
+	var doc Level
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err))
+		return level.Invalid, false
+	}
+
+	p := level.FromString(doc.Level)
+	if p == level.Invalid {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%q is not a valid level", doc.Level))
+		return level.Invalid, false
+	}
+	return p, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, doc any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
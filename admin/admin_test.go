@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/send"
+)
+
+func do(t *testing.T, h http.Handler, method, path, body string) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func decode[T any](t *testing.T, in []byte) T {
+	t.Helper()
+	var out T
+	if err := json.Unmarshal(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("Standard", func(t *testing.T) {
+		prev := grip.Sender().Priority()
+		t.Cleanup(func() {
+			grip.Sender().SetPriority(prev)
+			grip.Named("").SetThreshold(level.Invalid)
+		})
+
+		h := NewHandler()
+		code, body := do(t, h, http.MethodGet, "/level", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, prev.String())
+
+		code, body = do(t, h, http.MethodPut, "/level", `{"level":"debug"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "debug")
+		check.Equal(t, grip.Sender().Priority(), level.Debug)
+		check.Equal(t, grip.Named("").Threshold(), level.Debug)
+
+		code, _ = do(t, h, http.MethodPut, "/level", `{"level":"loud"}`)
+		check.Equal(t, code, http.StatusBadRequest)
+		code, _ = do(t, h, http.MethodPut, "/level", `not json`)
+		check.Equal(t, code, http.StatusBadRequest)
+		check.Equal(t, grip.Sender().Priority(), level.Debug)
+
+		code, _ = do(t, h, http.MethodPost, "/level", `{"level":"debug"}`)
+		check.Equal(t, code, http.StatusMethodNotAllowed)
+	})
+	t.Run("Loggers", func(t *testing.T) {
+		t.Cleanup(func() { grip.SetLevelRule("admin.test", level.Invalid) })
+		grip.Named("admin.test")
+
+		h := NewHandler()
+		code, body := do(t, h, http.MethodGet, "/loggers", "")
+		check.Equal(t, code, http.StatusOK)
+		var found bool
+		for _, l := range decode[[]Level](t, body) {
+			found = found || l.Name == "admin.test"
+		}
+		check.True(t, found)
+
+		code, _ = do(t, h, http.MethodGet, "/loggers/admin.missing", "")
+		check.Equal(t, code, http.StatusNotFound)
+
+		code, body = do(t, h, http.MethodPut, "/loggers/admin.test", `{"level":"alert"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "alert")
+
+		code, body = do(t, h, http.MethodGet, "/loggers/admin.test", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "alert")
+		check.Equal(t, grip.Named("admin.test").Threshold(), level.Alert)
+
+		code, _ = do(t, h, http.MethodDelete, "/loggers/admin.test", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, grip.Named("admin.test").Threshold(), grip.Sender().Priority())
+		code, _ = do(t, h, http.MethodDelete, "/loggers/admin.test", "")
+		check.Equal(t, code, http.StatusNotFound)
+	})
+	t.Run("Patterns", func(t *testing.T) {
+		t.Cleanup(func() { grip.SetLevelRule("admin.pattern.*", level.Invalid) })
+		h := NewHandler()
+
+		code, body := do(t, h, http.MethodPut, "/loggers/admin.pattern.*", `{"level":"error"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "error")
+		check.Equal(t, grip.LevelRules()["admin.pattern.*"], level.Error)
+		for name := range grip.NamedLoggers() {
+			if strings.HasPrefix(name, "admin.pattern") {
+				t.Errorf("setting a rule registered logger %q", name)
+			}
+		}
+
+		code, _ = do(t, h, http.MethodPut, "/loggers/admin*", `{"level":"error"}`)
+		check.Equal(t, code, http.StatusBadRequest)
+		_, ok := grip.LevelRules()["admin*"]
+		check.True(t, !ok)
+
+		code, body = do(t, h, http.MethodDelete, "/loggers/admin.pattern.*", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "error")
+		_, ok = grip.LevelRules()["admin.pattern.*"]
+		check.True(t, !ok)
+	})
+	t.Run("EffectiveLevels", func(t *testing.T) {
+		prev := grip.Sender()
+		t.Cleanup(func() {
+			grip.SetLevelRule("admin.effective", level.Invalid)
+			grip.Named("").SetThreshold(level.Invalid)
+			grip.SetSender(prev)
+		})
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		grip.SetSender(sender)
+		grip.Named("admin.effective")
+
+		h := NewHandler()
+		code, body := do(t, h, http.MethodPut, "/loggers/admin.effective", `{"level":"debug"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "debug")
+		check.True(t, grip.Named("admin.effective").Enabled(level.Debug))
+
+		code, body = do(t, h, http.MethodGet, "/level", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "info")
+		check.True(t, !grip.Named("").Enabled(level.Debug))
+
+		code, body = do(t, h, http.MethodPut, "/level", `{"level":"warning"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "warning")
+
+		code, body = do(t, h, http.MethodGet, "/loggers/admin.effective", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "debug")
+	})
+	t.Run("Senders", func(t *testing.T) {
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+
+		h := NewHandler()
+		h.RegisterSender("internal", sender)
+
+		code, body := do(t, h, http.MethodGet, "/senders", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, len(decode[[]Level](t, body)), 1)
+
+		code, body = do(t, h, http.MethodGet, "/senders/internal", "")
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, decode[Level](t, body).Level, "info")
+
+		code, _ = do(t, h, http.MethodPut, "/senders/internal", `{"level":"trace"}`)
+		check.Equal(t, code, http.StatusOK)
+		check.Equal(t, sender.Priority(), level.Trace)
+
+		code, _ = do(t, h, http.MethodPut, "/senders/internal", `{"level":""}`)
+		check.Equal(t, code, http.StatusBadRequest)
+		check.Equal(t, sender.Priority(), level.Trace)
+
+		h.UnregisterSender("internal")
+		code, _ = do(t, h, http.MethodGet, "/senders/internal", "")
+		check.Equal(t, code, http.StatusNotFound)
+		code, _ = do(t, h, http.MethodPut, "/senders/internal", `{"level":"info"}`)
+		check.Equal(t, code, http.StatusNotFound)
+	})
+}
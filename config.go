@@ -0,0 +1,257 @@
+package grip
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/send"
+)
+
+// Formats supported by Config. The empty format leaves the output's
+// default formatter in place, which for the standard outputs renders
+// the string form of the message.
+const (
+	FormatDefault  = "default"
+	FormatPlain    = "plain"
+	FormatJSON     = "json"
+	FormatLogfmt   = "logfmt"
+	FormatCallSite = "callsite"
+	FormatConsole  = "console"
+)
+
+// Outputs supported by Config without registration. Additional
+// outputs (e.g. "syslog" from the x/system package) are available
+// after they are registered with RegisterOutput.
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvName           = "GRIP_NAME"
+	EnvLevel          = "GRIP_LEVEL"
+	EnvFormat         = "GRIP_FORMAT"
+	EnvOutput         = "GRIP_OUTPUT"
+	EnvBufferSize     = "GRIP_BUFFER_SIZE"
+	EnvBufferInterval = "GRIP_BUFFER_INTERVAL"
+)
+
+// DefaultCallSiteDepth is the call site depth used by the call site
+// formatter when Config.CallSiteDepth is not set. It is correct for
+// messages logged using the methods on Logger; the package-level
+// logging functions require one additional frame.
+const DefaultCallSiteDepth = 5
+
+// Config declaratively describes the sender for a Logger. The zero
+// value describes the default configuration of the standard logger:
+// unformatted output to standard output at the Info level, named for
+// the current process.
+type Config struct {
+	// Name is the name of the sender, and defaults to the name of
+	// the process.
+	Name string
+	// Level is the threshold of the sender, and defaults to
+	// level.Info.
+	Level level.Priority
+	// Format is one of the Format constants.
+	Format string
+	// CallSiteDepth sets the depth for the call site format, and
+	// defaults to DefaultCallSiteDepth.
+	CallSiteDepth int
+	// Output is one of the Output constants or the name of an
+	// output registered with RegisterOutput, and defaults to
+	// standard output.
+	Output string
+	// Path is the name of the file for the file output.
+	Path string
+	// BufferSize and BufferInterval, when either is non-zero,
+	// wrap the output in a buffered sender (see
+	// send.MakeBuffered.)
+	BufferSize     int
+	BufferInterval time.Duration
+}
+
+// OutputFactory constructs the sender for an output. Factories
+// should only construct the output: the Config's name, level, format,
+// and buffering are applied to the sender after it's returned.
+type OutputFactory func(Config) (send.Sender, error)
+
+var outputs = &adt.SyncMap[string, OutputFactory]{}
+
+// RegisterOutput makes an output available to Config by name,
+// replacing any existing output with the same name. Packages in the
+// x/ hierarchy register outputs during init. The stdout, stderr, and
+// file outputs cannot be replaced.
+func RegisterOutput(name string, factory OutputFactory) { outputs.Store(name, factory) }
+
+// Configure builds the sender described by the configuration,
+// installs it as the sender of the standard logger, and returns the
+// standard logger. The configured level replaces any threshold set
+// on the standard logger.
+func Configure(conf Config) (Logger, error) {
+	s, err := conf.Build()
+	if err != nil {
+		return Logger{}, err
+	}
+	std.SetThreshold(level.Invalid)
+	std.SetSender(s)
+	return std, nil
+}
+
+// ConfigureFromEnv configures the standard logger (as Configure)
+// using the configuration read from the environment.
+func ConfigureFromEnv() (Logger, error) {
+	conf, err := ConfigFromEnv()
+	if err != nil {
+		return Logger{}, err
+	}
+	return Configure(conf)
+}
+
+// ConfigFromEnv reads a configuration from the GRIP_* environment
+// variables. Unset variables leave the corresponding field at its
+// default. GRIP_OUTPUT may specify a file output as "file:<path>",
+// and GRIP_BUFFER_INTERVAL is parsed with time.ParseDuration.
+func ConfigFromEnv() (Config, error) {
+	conf := Config{
+		Name:   os.Getenv(EnvName),
+		Format: os.Getenv(EnvFormat),
+		Output: os.Getenv(EnvOutput),
+	}
+
+	if val := os.Getenv(EnvLevel); val != "" {
+		if conf.Level = level.FromString(val); conf.Level == level.Invalid {
+			return Config{}, fmt.Errorf("%s=%q is not a valid level", EnvLevel, val)
+		}
+	}
+
+	if path, ok := strings.CutPrefix(conf.Output, OutputFile+":"); ok {
+		conf.Output = OutputFile
+		conf.Path = path
+	}
+
+	if val := os.Getenv(EnvBufferSize); val != "" {
+		size, err := strconv.Atoi(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s=%q: %w", EnvBufferSize, val, err)
+		}
+		conf.BufferSize = size
+	}
+
+	if val := os.Getenv(EnvBufferInterval); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s=%q: %w", EnvBufferInterval, val, err)
+		}
+		conf.BufferInterval = interval
+	}
+
+	return conf, nil
+}
+
+// Build constructs the sender described by the configuration.
+func (conf Config) Build() (send.Sender, error) {
+	fmtr, err := conf.formatter()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := conf.output()
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Name == "" {
+		conf.Name = defaultName()
+	}
+	if conf.Level == level.Invalid {
+		conf.Level = level.Info
+	}
+
+	s.SetName(conf.Name)
+	s.SetPriority(conf.Level)
+	if fmtr != nil {
+		s.SetFormatter(fmtr)
+	}
+
+	if conf.BufferSize != 0 || conf.BufferInterval != 0 {
+		s = send.MakeBuffered(s, conf.BufferInterval, conf.BufferSize)
+	}
+
+	return s, nil
+}
+
+func (conf Config) output() (send.Sender, error) {
+	switch conf.Output {
+	case "", OutputStdout:
+		return send.MakeStdOutput(), nil
+	case OutputStderr:
+		return send.MakeStdError(), nil
+	case OutputFile:
+		if conf.Path == "" {
+			return nil, fmt.Errorf("%s output requires a path", OutputFile)
+		}
+		return send.MakeFile(conf.Path)
+	}
+
+	factory, ok := outputs.Load(conf.Output)
+	if !ok {
+		return nil, fmt.Errorf("output %q is not registered", conf.Output)
+	}
+
+	s, err := factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("building %s output: %w", conf.Output, err)
+	}
+	return s, nil
+}
+
+func (conf Config) formatter() (send.MessageFormatter, error) {
+	switch strings.ToLower(conf.Format) {
+	case "":
+		return nil, nil
+	case FormatDefault:
+		return send.MakeDefaultFormatter(), nil
+	case FormatPlain:
+		return send.MakePlainFormatter(), nil
+	case FormatJSON:
+		return send.MakeJSONFormatter(), nil
+	case FormatLogfmt:
+		return send.MakeLogfmtFormatter(), nil
+	case FormatCallSite:
+		depth := conf.CallSiteDepth
+		if depth <= 0 {
+			depth = DefaultCallSiteDepth
+		}
+		return send.MakeCallSiteFormatter(depth), nil
+	case FormatConsole:
+		// colors are only enabled for the standard outputs,
+		// when they are terminals.
+		var color bool
+		switch conf.Output {
+		case "", OutputStdout:
+			color = send.ColorEnabled(os.Stdout)
+		case OutputStderr:
+			color = send.ColorEnabled(os.Stderr)
+		}
+		return send.MakeConsoleFormatter(send.ConsoleOptions{Color: color}), nil
+	default:
+		return nil, fmt.Errorf("format %q is not supported", conf.Format)
+	}
+}
+
+func defaultName() string {
+	if strings.Contains(os.Args[0], "go-build") {
+		return "grip"
+	}
+	return filepath.Base(os.Args[0])
+}
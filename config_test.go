@@ -0,0 +1,135 @@
+package grip
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		s, err := Config{}.Build()
+		check.NotError(t, err)
+		check.Equal(t, s.Priority(), level.Info)
+		check.Equal(t, s.Name(), defaultName())
+	})
+	t.Run("Options", func(t *testing.T) {
+		s, err := Config{Name: "svc", Level: level.Debug, Output: OutputStderr, Format: FormatJSON}.Build()
+		check.NotError(t, err)
+		check.Equal(t, s.Name(), "svc")
+		check.Equal(t, s.Priority(), level.Debug)
+		out, err := s.GetFormatter()(message.MakeString("hello"))
+		check.NotError(t, err)
+		check.True(t, strings.HasPrefix(out, "{"))
+	})
+	t.Run("Formats", func(t *testing.T) {
+		for _, f := range []string{"", FormatDefault, FormatPlain, FormatJSON, FormatLogfmt, FormatCallSite, FormatConsole, "JSON"} {
+			_, err := Config{Format: f}.Build()
+			check.NotError(t, err)
+		}
+		_, err := Config{Format: "xml"}.Build()
+		check.Error(t, err)
+	})
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.log")
+		s, err := Config{Output: OutputFile, Path: path, Format: FormatDefault}.Build()
+		check.NotError(t, err)
+		m := message.MakeString("in the file")
+		m.SetPriority(level.Error)
+		s.Send(m)
+		check.NotError(t, s.Close())
+		data, err := os.ReadFile(path)
+		check.NotError(t, err)
+		check.Equal(t, string(data), "[p=error]: in the file\n")
+
+		_, err = Config{Output: OutputFile}.Build()
+		check.Error(t, err)
+	})
+	t.Run("Buffered", func(t *testing.T) {
+		s, err := Config{BufferSize: 10, BufferInterval: time.Minute}.Build()
+		check.NotError(t, err)
+		defer s.Close()
+		_, ok := s.(interface{ Unwrap() send.Sender })
+		check.True(t, ok)
+		check.Equal(t, s.Priority(), level.Info)
+	})
+	t.Run("RegisteredOutput", func(t *testing.T) {
+		_, err := Config{Output: "test-output"}.Build()
+		check.Error(t, err)
+
+		internal := send.MakeInternal()
+		RegisterOutput("test-output", func(conf Config) (send.Sender, error) { return internal, nil })
+		s, err := Config{Output: "test-output", Name: "registered"}.Build()
+		check.NotError(t, err)
+		check.True(t, s == send.Sender(internal))
+		check.Equal(t, internal.Name(), "registered")
+
+		expected := errors.New("failed")
+		RegisterOutput("test-output", func(conf Config) (send.Sender, error) { return nil, expected })
+		_, err = Config{Output: "test-output"}.Build()
+		check.ErrorIs(t, err, expected)
+	})
+	t.Run("Env", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "env.log")
+		t.Setenv(EnvName, "env-service")
+		t.Setenv(EnvLevel, "warning")
+		t.Setenv(EnvFormat, FormatPlain)
+		t.Setenv(EnvOutput, "file:"+path)
+		t.Setenv(EnvBufferSize, "20")
+		t.Setenv(EnvBufferInterval, "10s")
+
+		conf, err := ConfigFromEnv()
+		check.NotError(t, err)
+		check.Equal(t, conf, Config{
+			Name:           "env-service",
+			Level:          level.Warning,
+			Format:         FormatPlain,
+			Output:         OutputFile,
+			Path:           path,
+			BufferSize:     20,
+			BufferInterval: 10 * time.Second,
+		})
+	})
+	t.Run("EnvErrors", func(t *testing.T) {
+		for key, val := range map[string]string{
+			EnvLevel:          "loud",
+			EnvBufferSize:     "many",
+			EnvBufferInterval: "soon",
+		} {
+			t.Run(key, func(t *testing.T) {
+				t.Setenv(key, val)
+				_, err := ConfigFromEnv()
+				check.Error(t, err)
+				_, err = ConfigureFromEnv()
+				check.Error(t, err)
+			})
+		}
+	})
+	t.Run("Configure", func(t *testing.T) {
+		prev := Sender()
+		t.Cleanup(func() { SetSender(prev) })
+
+		logger, err := Configure(Config{Name: "configured", Level: level.Alert})
+		check.NotError(t, err)
+		check.True(t, logger == std)
+		check.Equal(t, Sender().Name(), "configured")
+		check.Equal(t, Sender().Priority(), level.Alert)
+
+		_, err = Configure(Config{Output: "nowhere"})
+		check.Error(t, err)
+		check.Equal(t, Sender().Name(), "configured")
+
+		t.Setenv(EnvLevel, "debug")
+		_, err = ConfigureFromEnv()
+		check.NotError(t, err)
+		check.Equal(t, Sender().Priority(), level.Debug)
+	})
+}
@@ -38,7 +38,8 @@ func WithContextLogger(ctx context.Context, name string, logger Logger) context.
 
 // ContextLogger produces a logger stored in the context by a given
 // name. If such a context is not stored the standard/default logger
-// is returned.
+// is returned. If the context has fields (see WithFields), the
+// logger annotates all messages with these fields.
 func ContextLogger(ctx context.Context, name string) Logger {
 	if ctx == nil {
 		return std
@@ -46,9 +47,9 @@ func ContextLogger(ctx context.Context, name string) Logger {
 
 	val := ctx.Value(ctxKey(name))
 	if l, ok := val.(Logger); ok {
-		return l
+		return l.withFields(contextFields(ctx))
 	}
-	return std
+	return std.withFields(contextFields(ctx))
 }
 
 // WithNewContextLogger checks if a logger is configured with a
@@ -77,3 +78,11 @@ func HasContextLogger(ctx context.Context, name string) bool {
 // HasLogger returns true when the default context logger is
 // attached.
 func HasLogger(ctx context.Context) bool { return HasContextLogger(ctx, string(defaultContextKey)) }
+
+// WithNamedLogger attaches the named logger (see Named) to the
+// context using the logger's name as the context key, so that it can
+// be retrieved with ContextLogger(ctx, name). If a logger with this
+// name is already attached, the existing context is returned.
+func WithNamedLogger(ctx context.Context, name string) context.Context {
+	return WithContextLogger(ctx, name, Named(name))
+}
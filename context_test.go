@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
 	"github.com/tychoish/grip/send"
 )
 
@@ -42,3 +44,76 @@ func TestContext(t *testing.T) {
 	})
 
 }
+
+type ctxTestKey struct{}
+
+type ctxRecordingSender struct {
+	send.Base
+	values []any
+}
+
+func (s *ctxRecordingSender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+func (s *ctxRecordingSender) SendContext(ctx context.Context, m message.Composer) {
+	if send.ShouldLog(s, m) {
+		s.values = append(s.values, ctx.Value(ctxTestKey{}))
+	}
+}
+
+func TestContextMethods(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxTestKey{}, "value")
+
+	t.Run("ContextSender", func(t *testing.T) {
+		rec := &ctxRecordingSender{}
+		rec.SetPriority(level.Trace)
+		logger := NewLogger(rec)
+
+		for _, fn := range []func(context.Context, any){
+			logger.EmergencyCtx, logger.AlertCtx, logger.CriticalCtx,
+			logger.ErrorCtx, logger.WarningCtx, logger.NoticeCtx,
+			logger.InfoCtx, logger.DebugCtx, logger.TraceCtx,
+		} {
+			fn(ctx, "message")
+		}
+		logger.LogCtx(ctx, level.Info, "message")
+		logger.SendCtx(ctx, message.MakeString("unleveled"))
+		logger.Info("no context")
+
+		check.Equal(t, len(rec.values), 11)
+		for _, val := range rec.values[:10] {
+			check.Equal(t, val, any("value"))
+		}
+		check.True(t, rec.values[10] == nil)
+	})
+	t.Run("Fallback", func(t *testing.T) {
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		logger := NewLogger(sender).With("a", 1)
+
+		logger.InfoCtx(WithFields(ctx, "b", 2), message.NewKV().KV("msg", "hi"))
+		logger.DebugCtx(ctx, "dropped")
+		check.Equal(t, sender.Len(), 1)
+		check.Equal(t, sender.GetMessage().Rendered, "msg='hi' a='1' b='2'")
+
+		// the logger's own fields are not changed by the context
+		logger.Info(message.NewKV().KV("msg", "again"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='again' a='1'")
+	})
+	t.Run("Standard", func(t *testing.T) {
+		prev := Sender()
+		defer SetSender(prev)
+
+		rec := &ctxRecordingSender{}
+		rec.SetPriority(level.Trace)
+		SetSender(rec)
+
+		for _, fn := range []func(context.Context, any){
+			EmergencyCtx, AlertCtx, CriticalCtx, ErrorCtx, WarningCtx,
+			NoticeCtx, InfoCtx, DebugCtx, TraceCtx,
+		} {
+			fn(ctx, "message")
+		}
+		LogCtx(ctx, level.Info, "message")
+		SendCtx(ctx, message.MakeString("unleveled"))
+		check.Equal(t, len(rec.values), 10)
+	})
+}
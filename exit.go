@@ -0,0 +1,136 @@
+package grip
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+// Fatal and Panic Handling
+//
+// Before the Panic methods panic, and before the Fatal methods exit,
+// the logger flushes its sender so that buffered and asynchronous
+// senders deliver the message that caused the process to
+// terminate. Because a flush may block indefinitely (e.g. on a
+// network sender,) flushes are bounded by the flush timeout (see
+// SetFlushTimeout.) Flushes and exit hooks run in a separate
+// goroutine, so the timeout holds even for senders and hooks that
+// ignore the context: when it expires, the process exits (or panics)
+// without waiting for them to return.
+//
+// Before exiting, the Fatal methods also run the hooks registered
+// with RegisterExitHook, which allows applications to release
+// resources or flush other senders. Exit hooks do not run for Panic
+// methods, because the panic may be recovered.
+
+// DefaultFlushTimeout is the default upper bound for the time spent
+// flushing the sender and running exit hooks before a Fatal method
+// exits or a Panic method panics.
+const DefaultFlushTimeout = 5 * time.Second
+
+var exit = &exitHandler{
+	exit:    adt.NewAtomic(os.Exit),
+	timeout: adt.NewAtomic(DefaultFlushTimeout),
+}
+
+type exitHandler struct {
+	mu      sync.Mutex
+	hooks   []func(context.Context)
+	exit    *adt.Atomic[func(int)]
+	timeout *adt.Atomic[time.Duration]
+}
+
+// RegisterExitHook adds a function that runs before the Fatal
+// methods exit the process, after the fatal message is sent. Hooks
+// run in the order they were registered, and share a context that is
+// canceled when the flush timeout expires. Hooks run before the
+// sender is flushed, so messages that hooks log are delivered.
+func RegisterExitHook(hook func(context.Context)) {
+	if hook == nil {
+		return
+	}
+	exit.mu.Lock()
+	defer exit.mu.Unlock()
+	exit.hooks = append(exit.hooks, hook)
+}
+
+// SetExitFunction replaces the function the Fatal methods use to
+// exit the process, which is os.Exit by default. Passing nil restores
+// os.Exit. This is primarily useful in tests.
+func SetExitFunction(fn func(code int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exit.exit.Set(fn)
+}
+
+// SetFlushTimeout sets the upper bound for the time spent flushing
+// the sender (and running exit hooks) before a Fatal method exits or
+// a Panic method panics. Values less than or equal to zero restore
+// DefaultFlushTimeout.
+func SetFlushTimeout(dur time.Duration) {
+	if dur <= 0 {
+		dur = DefaultFlushTimeout
+	}
+	exit.timeout.Set(dur)
+}
+
+func (e *exitHandler) flush(ctx context.Context, s send.Sender, m message.Composer) {
+	if err := s.Flush(ctx); err != nil {
+		s.GetErrorHandler()(send.WrapError(err, m))
+	}
+}
+
+func (e *exitHandler) runHooks(ctx context.Context) {
+	e.mu.Lock()
+	hooks := append([]func(context.Context){}, e.hooks...)
+	e.mu.Unlock()
+
+	for _, hook := range hooks {
+		if ctx.Err() != nil {
+			return
+		}
+		hook(ctx)
+	}
+}
+
+// within runs the function in its own goroutine, and returns when
+// the function returns or the context ends, whichever is first.
+func within(ctx context.Context, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// panic flushes the sender, within the flush timeout.
+func (e *exitHandler) panic(s send.Sender, m message.Composer) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout.Get())
+	defer cancel()
+
+	within(ctx, func() { e.flush(ctx, s, m) })
+}
+
+// fatal runs the exit hooks and flushes the sender, within the flush
+// timeout, and then exits the process.
+func (e *exitHandler) fatal(s send.Sender, m message.Composer) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout.Get())
+	within(ctx, func() {
+		e.runHooks(ctx)
+		e.flush(ctx, s, m)
+	})
+	cancel()
+
+	e.exit.Get()(1)
+}
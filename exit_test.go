@@ -0,0 +1,150 @@
+package grip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+type flushRecordingSender struct {
+	send.Base
+	sent    []string
+	flushes int
+	block   bool
+	stuck   chan struct{}
+}
+
+func (s *flushRecordingSender) Send(m message.Composer) {
+	if send.ShouldLog(s, m) {
+		s.sent = append(s.sent, m.String())
+	}
+}
+
+func (s *flushRecordingSender) Flush(ctx context.Context) error {
+	s.flushes++
+	switch {
+	case s.stuck != nil:
+		// ignores the context
+		<-s.stuck
+	case s.block:
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func withExitHandler(t *testing.T) *[]int {
+	t.Helper()
+
+	prev := exit
+	exit = &exitHandler{exit: prev.exit, timeout: prev.timeout}
+	codes := &[]int{}
+	SetExitFunction(func(code int) { *codes = append(*codes, code) })
+
+	t.Cleanup(func() {
+		SetExitFunction(nil)
+		SetFlushTimeout(0)
+		exit = prev
+	})
+	return codes
+}
+
+func TestExitHandling(t *testing.T) {
+	t.Run("FatalFlushesAndRunsHooks", func(t *testing.T) {
+		codes := withExitHandler(t)
+		s := &flushRecordingSender{}
+		s.SetPriority(level.Info)
+		logger := NewLogger(s)
+
+		var order []string
+		RegisterExitHook(func(context.Context) { order = append(order, "first") })
+		RegisterExitHook(func(context.Context) { order = append(order, "second"); logger.Info("from hook") })
+		RegisterExitHook(nil)
+
+		logger.EmergencyFatal("goodbye")
+
+		check.EqualItems(t, *codes, []int{1})
+		check.EqualItems(t, order, []string{"first", "second"})
+		check.EqualItems(t, s.sent, []string{"goodbye", "from hook"})
+		check.Equal(t, s.flushes, 1)
+	})
+	t.Run("FatalFiltered", func(t *testing.T) {
+		codes := withExitHandler(t)
+		s := &flushRecordingSender{}
+		s.SetPriority(level.Warning)
+		ran := false
+		RegisterExitHook(func(context.Context) { ran = true })
+
+		NewLogger(s).sendFatal(level.Info, message.MakeString("quiet"))
+
+		check.Equal(t, len(*codes), 0)
+		check.True(t, !ran)
+		check.Equal(t, s.flushes, 0)
+	})
+	t.Run("FlushTimeout", func(t *testing.T) {
+		codes := withExitHandler(t)
+		SetFlushTimeout(10 * time.Millisecond)
+
+		errs := make(chan error, 1)
+		s := &flushRecordingSender{block: true}
+		s.SetPriority(level.Info)
+		s.SetErrorHandler(func(err error) { errs <- err })
+
+		hookCtx := make(chan context.Context, 1)
+		RegisterExitHook(func(ctx context.Context) { hookCtx <- ctx })
+
+		start := time.Now()
+		NewLogger(s).EmergencyFatal("goodbye")
+
+		check.True(t, time.Since(start) < time.Second)
+		check.EqualItems(t, *codes, []int{1})
+		check.ErrorIs(t, <-errs, context.DeadlineExceeded)
+		check.True(t, (<-hookCtx).Err() != nil)
+	})
+	t.Run("IgnoresContext", func(t *testing.T) {
+		codes := withExitHandler(t)
+		SetFlushTimeout(10 * time.Millisecond)
+
+		stuck := make(chan struct{})
+		defer close(stuck)
+		s := &flushRecordingSender{stuck: stuck}
+		s.SetPriority(level.Info)
+
+		start := time.Now()
+		func() {
+			defer func() { check.True(t, recover() != nil) }()
+			NewLogger(s).EmergencyPanic("flush")
+		}()
+		check.True(t, time.Since(start) < time.Second)
+
+		s = &flushRecordingSender{}
+		s.SetPriority(level.Info)
+		RegisterExitHook(func(context.Context) { <-stuck })
+		start = time.Now()
+		NewLogger(s).EmergencyFatal("hook")
+		check.True(t, time.Since(start) < time.Second)
+		check.EqualItems(t, *codes, []int{1})
+	})
+	t.Run("PanicFlushesWithoutHooks", func(t *testing.T) {
+		codes := withExitHandler(t)
+		s := &flushRecordingSender{}
+		s.SetPriority(level.Info)
+		ran := false
+		RegisterExitHook(func(context.Context) { ran = true })
+
+		func() {
+			defer func() { check.True(t, recover() != nil) }()
+			NewLogger(s).EmergencyPanic("goodbye")
+		}()
+
+		check.Equal(t, len(*codes), 0)
+		check.True(t, !ran)
+		check.Equal(t, s.flushes, 1)
+		check.EqualItems(t, s.sent, []string{"goodbye"})
+	})
+}
@@ -0,0 +1,135 @@
+package grip
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/tychoish/fun/irt"
+	"github.com/tychoish/grip/message"
+)
+
+const fieldsContextKey ctxKey = "__GRIP_CONTEXT_FIELDS"
+
+// BadKey is the key used for the final value in a list of key/value
+// pairs that has an odd number of elements.
+const BadKey = "!BADKEY"
+
+// fieldSet is an immutable list of key/value pairs that a Logger
+// adds to every message it sends.
+type fieldSet struct{ pairs []irt.KV[string, any] }
+
+// WithFields attaches key/value pairs to the context. Loggers
+// resolved from the context (with Context or ContextLogger) annotate
+// every message they send with these fields. Keys are converted to
+// strings with fmt.Sprint; if there is an odd number of arguments,
+// the last value uses the BadKey key.
+//
+// Fields accumulate: calling WithFields on a context that already has
+// fields adds to them, and later values override earlier values with
+// the same key, for message types that support overriding.
+func WithFields(ctx context.Context, kv ...any) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, fieldsContextKey, contextFields(ctx).extend(pairsFromArgs(kv)))
+}
+
+// ContextFields returns the fields attached to the context using
+// WithFields.
+func ContextFields(ctx context.Context) iter.Seq2[string, any] { return contextFields(ctx).iterator() }
+
+func contextFields(ctx context.Context) *fieldSet {
+	if ctx == nil {
+		return nil
+	}
+	fs, _ := ctx.Value(fieldsContextKey).(*fieldSet)
+	return fs
+}
+
+func pairsFromArgs(kv []any) []irt.KV[string, any] {
+	out := make([]irt.KV[string, any], 0, (len(kv)+1)/2)
+	for idx := 0; idx < len(kv); idx += 2 {
+		if idx+1 == len(kv) {
+			out = append(out, irt.MakeKV[string, any](BadKey, kv[idx]))
+			break
+		}
+
+		key, ok := kv[idx].(string)
+		if !ok {
+			key = fmt.Sprint(kv[idx])
+		}
+		out = append(out, irt.MakeKV(key, kv[idx+1]))
+	}
+	return out
+}
+
+// extend returns a new field set with the pairs added to the end of
+// the existing fields; the receiver may be nil.
+func (fs *fieldSet) extend(pairs []irt.KV[string, any]) *fieldSet {
+	switch {
+	case len(pairs) == 0:
+		return fs
+	case fs == nil:
+		return &fieldSet{pairs: pairs}
+	default:
+		return &fieldSet{pairs: append(slices.Clip(fs.pairs), pairs...)}
+	}
+}
+
+func (fs *fieldSet) iterator() iter.Seq2[string, any] {
+	if fs == nil {
+		return func(func(string, any) bool) {}
+	}
+	return irt.KVsplit(irt.Slice(fs.pairs))
+}
+
+func (fs *fieldSet) annotate(m message.Composer) {
+	if fs == nil {
+		return
+	}
+	for _, kv := range fs.pairs {
+		m.Annotate(kv.Key, kv.Value)
+	}
+}
+
+// With returns a copy of the logger that annotates every message it
+// sends with the key/value pairs, in addition to any fields the
+// logger already has. Keys are converted as in WithFields. Chained
+// calls accumulate fields, and later values override earlier values
+// with the same key, for message types that support overriding.
+//
+// Group messages (and messages produced by the Build method) annotate
+// each of their constituent messages. The returned logger shares the
+// sender, converter, and threshold of the original logger.
+func (g Logger) With(kv ...any) Logger { return g.withFields(&fieldSet{pairs: pairsFromArgs(kv)}) }
+
+// WithKV is equivalent to With, using the pairs in the KV message.
+// The pairs are copied, so later changes to the KV message do not
+// affect the logger.
+func (g Logger) WithKV(kvs *message.KV) Logger {
+	if kvs == nil {
+		return g
+	}
+	return g.withFields(&fieldSet{pairs: slices.Collect(irt.KVjoin(kvs.Iterator()))})
+}
+
+// With returns a copy of the standard logger with the key/value
+// pairs; see Logger.With.
+func With(kv ...any) Logger { return std.With(kv...) }
+
+// WithKV returns a copy of the standard logger with the key/value
+// pairs; see Logger.WithKV.
+func WithKV(kvs *message.KV) Logger { return std.WithKV(kvs) }
+
+// withFields returns a copy of the logger that annotates messages
+// with the fields, in addition to the logger's existing fields.
+func (g Logger) withFields(fs *fieldSet) Logger {
+	if fs == nil || len(fs.pairs) == 0 {
+		return g
+	}
+	g.fields = g.fields.extend(fs.pairs)
+	return g
+}
@@ -0,0 +1,165 @@
+package grip
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+func TestContextFields(t *testing.T) {
+	t.Run("NoFields", func(t *testing.T) {
+		ctx := context.Background()
+		check.True(t, WithFields(ctx) == ctx)
+		check.True(t, Context(ctx) == std)
+		check.Equal(t, len(maps.Collect(ContextFields(ctx))), 0)
+		check.Equal(t, len(maps.Collect(ContextFields(nil))), 0) //nolint:staticcheck
+	})
+	t.Run("Accumulate", func(t *testing.T) {
+		ctx := WithFields(context.Background(), "request", "r-1", "tenant", 42)
+		child := WithFields(ctx, "trace", "t-1", "odd")
+		check.Equal(t, len(maps.Collect(ContextFields(ctx))), 2)
+
+		fields := maps.Collect(ContextFields(child))
+		check.Equal(t, len(fields), 4)
+		check.Equal(t, fields["tenant"], any(42))
+		check.Equal(t, fields["trace"], any("t-1"))
+		check.Equal(t, fields[BadKey], any("odd"))
+
+		// siblings do not share storage
+		sibling := WithFields(ctx, "trace", "t-2")
+		check.Equal(t, maps.Collect(ContextFields(sibling))["trace"], any("t-2"))
+		check.Equal(t, maps.Collect(ContextFields(child))["trace"], any("t-1"))
+	})
+	t.Run("Annotates", func(t *testing.T) {
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		ctx := WithLogger(context.Background(), NewLogger(sender))
+		ctx = WithFields(ctx, "request", "r-1", 7, "seven")
+
+		logger := Context(ctx)
+		logger.Info(message.Fields{"msg": "hello"})
+		msg := sender.GetMessage()
+		check.Substring(t, msg.Rendered, "request='r-1'")
+		check.Substring(t, msg.Rendered, "7='seven'")
+		check.Substring(t, msg.Rendered, "msg='hello'")
+
+		logger.Build().KV("msg", "built").Level(level.Error).Send()
+		check.Substring(t, sender.GetMessage().Rendered, "request='r-1'")
+
+		group := message.BuildGroupComposer(message.NewKV().KV("a", 1), message.NewKV().KV("b", 2))
+		logger.Log(level.Notice, group)
+		msg = sender.GetMessage()
+		check.Equal(t, strings.Count(msg.Rendered, "request='r-1'"), 2)
+
+		// messages below the threshold are not annotated
+		below := message.NewKV().KV("msg", "quiet")
+		logger.Debug(below)
+		check.True(t, !strings.Contains(below.String(), "request"))
+		check.Equal(t, sender.Len(), 0)
+	})
+	t.Run("NamedContextLogger", func(t *testing.T) {
+		ctx := WithFields(WithNamedLogger(context.Background(), "test.fields"), "k", "v")
+		logger := ContextLogger(ctx, "test.fields")
+		check.Equal(t, logger.Name(), "test.fields")
+		check.Equal(t, maps.Collect(logger.fields.iterator())["k"], any("v"))
+		check.True(t, logger.Clone().fields == logger.fields)
+	})
+}
+
+func TestLoggerWith(t *testing.T) {
+	setup := func(t *testing.T) (*send.InternalSender, Logger) {
+		t.Helper()
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		return sender, NewLogger(sender)
+	}
+
+	t.Run("Chained", func(t *testing.T) {
+		sender, logger := setup(t)
+		base := logger.With("component", "x")
+		child := base.With("sub", "y", "component", "z")
+
+		base.Info(message.NewKV().KV("msg", "base"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='base' component='x'")
+
+		child.Info(message.NewKV().KV("msg", "child"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='child' component='z' sub='y'")
+
+		logger.Info(message.NewKV().KV("msg", "plain"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='plain'")
+		check.True(t, logger.With() == logger)
+	})
+	t.Run("WithKV", func(t *testing.T) {
+		sender, logger := setup(t)
+		kvs := message.NewKV().KV("a", 1).KV("b", 2)
+		child := logger.WithKV(kvs)
+		kvs.KV("c", 3)
+
+		child.Info(message.NewKV().KV("msg", "kv"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='kv' a='1' b='2'")
+		check.True(t, logger.WithKV(nil) == logger)
+	})
+	t.Run("Group", func(t *testing.T) {
+		sender, logger := setup(t)
+		logger.With("k", "v").Info(message.BuildGroupComposer(
+			message.NewKV().KV("msg", "one"),
+			message.NewKV().KV("msg", "two"),
+		))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='one' k='v'\nmsg='two' k='v'")
+	})
+	t.Run("Builder", func(t *testing.T) {
+		sender, logger := setup(t)
+		child := logger.With("k", "v")
+		child.Build().KV("msg", "one").Level(level.Info).Send()
+		check.Equal(t, sender.GetMessage().Rendered, "msg='one' k='v'")
+
+		child.Build().KV("msg", "a").KV("n", 1).Level(level.Info).Group().Send()
+		check.Equal(t, sender.GetMessage().Rendered, "msg='a' n='1' k='v'")
+	})
+	t.Run("WithContext", func(t *testing.T) {
+		sender, logger := setup(t)
+		ctx := WithLogger(context.Background(), logger.With("a", 1))
+		ctx = WithFields(ctx, "b", 2)
+		Context(ctx).Info(message.NewKV().KV("msg", "ctx"))
+		check.Equal(t, sender.GetMessage().Rendered, "msg='ctx' a='1' b='2'")
+	})
+	t.Run("Redacting", func(t *testing.T) {
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		redacting := send.MakeRedacting(sender, message.DefaultRedactor())
+		redacting.SetFormatter(send.MakeJSONFormatter())
+		logger := NewLogger(redacting).With("token", "abc123", "user", "jo")
+
+		for _, msg := range []any{
+			"hello",
+			message.MakeError(errors.New("failed")),
+			message.Fields{"msg": "fields"},
+			message.NewKV().KV("msg", "kv"),
+		} {
+			logger.Info(msg)
+			out, err := sender.GetFormatter()(sender.GetMessage().Message)
+			check.NotError(t, err)
+			check.True(t, !strings.Contains(out, "abc123"))
+			check.Substring(t, out, `"token":"[REDACTED]"`)
+			check.Substring(t, out, `"user":"jo"`)
+		}
+
+		ctx := WithFields(WithLogger(context.Background(), NewLogger(redacting)), "password", "hunter2")
+		Context(ctx).Error(errors.New("failed"))
+		out, err := sender.GetFormatter()(sender.GetMessage().Message)
+		check.NotError(t, err)
+		check.True(t, !strings.Contains(out, "hunter2"))
+	})
+	t.Run("Standard", func(t *testing.T) {
+		check.Equal(t, maps.Collect(With("a", 1).fields.iterator())["a"], any(1))
+		check.Equal(t, maps.Collect(WithKV(message.NewKV().KV("b", 2)).fields.iterator())["b"], any(2))
+		check.True(t, std.fields == nil)
+	})
+}
@@ -33,7 +33,9 @@ const (
 )
 
 // String implements the Stringer interface and makes it possible to
-// print human-readable string identifier for a log level.
+// print human-readable string identifier for a log level. Custom
+// levels use their registered name, and other values are rendered
+// as "level.Priority<N>".
 func (p Priority) String() string {
 	switch p {
 	case Emergency:
@@ -57,11 +59,18 @@ func (p Priority) String() string {
 	case Invalid:
 		return "invalid"
 	default:
+		if name, ok := customLevelName(p); ok {
+			return name
+		}
 		return fmt.Sprintf("level.Priority<%d>", uint8(p))
 	}
 }
 
-// FromString takes a string, (case insensitive, leading and trailing space removed, )
+// FromString takes a string, (case insensitive, leading and trailing
+// space removed,) and returns the priority with that name, including
+// registered custom levels (see Register.) Numeric strings, and the
+// "level.Priority<N>" format produced by String, are parsed as the
+// numeric value of the priority. Unknown names return Invalid.
 func FromString(l string) Priority {
 	l = strings.TrimSpace(strings.ToLower(l))
 	switch l {
@@ -86,6 +95,9 @@ func FromString(l string) Priority {
 	case "invalid":
 		return Invalid
 	default:
+		if p, ok := customLevelValue(l); ok {
+			return p
+		}
 		if strings.HasPrefix(l, "level.priority<") && strings.HasSuffix(l, ">") {
 			l = l[15 : len(l)-1]
 		}
@@ -0,0 +1,97 @@
+package level
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse is a strict form of FromString: it returns an error, rather
+// than Invalid, for strings that do not name a priority. Parse
+// accepts the names of the standard and registered custom levels
+// (case insensitive,) decimal values between 1 and 255, and the
+// "level.Priority<N>" form produced by String. The string "invalid"
+// parses as Invalid, so that every value produced by String parses.
+func Parse(in string) (Priority, error) {
+	name := strings.TrimSpace(strings.ToLower(in))
+	if name == "invalid" {
+		return Invalid, nil
+	}
+
+	// names of the standard and custom levels
+	if p := FromString(name); p != Invalid && p.String() == name {
+		return p, nil
+	}
+
+	if inner, ok := strings.CutPrefix(name, "level.priority<"); ok {
+		name = strings.TrimSuffix(inner, ">")
+	}
+	if val, err := strconv.ParseUint(name, 10, 8); err == nil && val > 0 {
+		return Priority(val), nil
+	}
+
+	return Invalid, fmt.Errorf("%q is not a valid priority", in)
+}
+
+// MarshalText implements encoding.TextMarshaler, using String.
+func (p Priority) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler, using Parse.
+func (p *Priority) UnmarshalText(in []byte) error {
+	out, err := Parse(string(in))
+	if err != nil {
+		return err
+	}
+	*p = out
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the priority as a
+// string (see String.) Before Priority implemented json.Marshaler,
+// priorities were encoded as numbers: use Number for fields that
+// must keep the numeric form (as message.Base does.) UnmarshalJSON
+// accepts both forms.
+func (p Priority) MarshalJSON() ([]byte, error) { return json.Marshal(p.String()) }
+
+// UnmarshalJSON implements json.Unmarshaler. Priorities may be
+// encoded either as strings, which are parsed with Parse, or as
+// numbers, which must be between 1 and 255 as with Parse. Null leaves
+// the priority unchanged.
+func (p *Priority) UnmarshalJSON(in []byte) error {
+	in = bytes.TrimSpace(in)
+	switch {
+	case bytes.Equal(in, []byte("null")):
+		return nil
+	case len(in) > 0 && in[0] == '"':
+		var str string
+		if err := json.Unmarshal(in, &str); err != nil {
+			return err
+		}
+		return p.UnmarshalText([]byte(str))
+	default:
+		var num uint8
+		if err := json.Unmarshal(in, &num); err != nil || num == 0 {
+			return fmt.Errorf("%s is not a valid priority", in)
+		}
+		*p = Priority(num)
+		return nil
+	}
+}
+
+// Number is a Priority that is encoded as a number in JSON, which was
+// the form of all priorities before Priority implemented
+// json.Marshaler. Numbers decode from either form, as with Priority.
+type Number Priority
+
+// MarshalJSON implements json.Marshaler, encoding the priority as a
+// number.
+func (n Number) MarshalJSON() ([]byte, error) { return strconv.AppendUint(nil, uint64(n), 10), nil }
+
+// UnmarshalJSON implements json.Unmarshaler, as Priority.UnmarshalJSON.
+func (n *Number) UnmarshalJSON(in []byte) error { return (*Priority)(n).UnmarshalJSON(in) }
+
+// Set implements flag.Value, using Parse, so that *Priority values
+// can be used with flag.Var.
+func (p *Priority) Set(in string) error { return p.UnmarshalText([]byte(in)) }
@@ -0,0 +1,116 @@
+package level
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Cleanup(func() { Unregister("audit") })
+	MustRegister("audit", 190)
+
+	for in, out := range map[string]Priority{
+		"info":               Info,
+		" Warning ":          Warning,
+		"TRACE":              Trace,
+		"audit":              190,
+		"invalid":            Invalid,
+		"42":                 42,
+		"255":                255,
+		"level.Priority<42>": 42,
+	} {
+		p, err := Parse(in)
+		assert(t, err == nil, in, err)
+		assert(t, p == out, in, p, out)
+	}
+
+	for _, in := range []string{"", "  ", "bob", "0", "256", "-1", "+10", "level.Priority<bob>", "<42>"} {
+		p, err := Parse(in)
+		assert(t, err != nil, in)
+		assert(t, p == Invalid, in, p)
+	}
+
+	for i := range 256 {
+		p, err := Parse(Priority(i).String())
+		assert(t, err == nil, i, err)
+		assert(t, p == Priority(i), i, p)
+	}
+}
+
+func TestMarshaling(t *testing.T) {
+	t.Cleanup(func() { Unregister("verbose") })
+	MustRegister("verbose", 75)
+
+	type config struct {
+		Level   Priority  `json:"level"`
+		Default *Priority `json:"default,omitempty"`
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		out, err := json.Marshal(config{Level: Warning})
+		assert(t, err == nil, err)
+		assert(t, string(out) == `{"level":"warning"}`, string(out))
+
+		out, err = json.Marshal(Priority(75))
+		assert(t, err == nil, err)
+		assert(t, string(out) == `"verbose"`, string(out))
+
+		var conf config
+		assert(t, json.Unmarshal([]byte(`{"level":"Debug","default":"verbose"}`), &conf) == nil)
+		assert(t, conf.Level == Debug, conf.Level)
+		assert(t, conf.Default != nil && *conf.Default == 75)
+
+		// numbers are accepted for compatibility
+		assert(t, json.Unmarshal([]byte(`{"level":150}`), &conf) == nil)
+		assert(t, conf.Level == Warning, conf.Level)
+
+		// null leaves the value unchanged
+		assert(t, json.Unmarshal([]byte(`{"level":null}`), &conf) == nil)
+		assert(t, conf.Level == Warning, conf.Level)
+
+		for _, doc := range []string{`{"level":"loud"}`, `{"level":""}`, `{"level":0}`, `{"level":256}`, `{"level":-1}`, `{"level":true}`} {
+			assert(t, json.Unmarshal([]byte(doc), &conf) != nil, doc)
+		}
+	})
+	t.Run("Number", func(t *testing.T) {
+		var doc struct {
+			Level Number `json:"level"`
+		}
+		doc.Level = Number(Warning)
+		out, err := json.Marshal(doc)
+		assert(t, err == nil, err)
+		assert(t, string(out) == `{"level":150}`, string(out))
+
+		for _, in := range []string{`{"level":100}`, `{"level":"info"}`} {
+			assert(t, json.Unmarshal([]byte(in), &doc) == nil, in)
+			assert(t, Priority(doc.Level) == Info, in, doc.Level)
+		}
+		assert(t, json.Unmarshal([]byte(`{"level":0}`), &doc) != nil)
+	})
+	t.Run("Text", func(t *testing.T) {
+		out, err := Alert.MarshalText()
+		assert(t, err == nil, err)
+		assert(t, string(out) == "alert")
+
+		var p Priority
+		assert(t, p.UnmarshalText([]byte("notice")) == nil)
+		assert(t, p == Notice)
+		assert(t, p.UnmarshalText([]byte("nope")) != nil)
+		assert(t, p == Notice)
+	})
+	t.Run("Flag", func(t *testing.T) {
+		p := Info
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(&p, "level", "log level")
+
+		assert(t, fs.Parse([]string{"-level", "error"}) == nil)
+		assert(t, p == Error, p)
+		assert(t, fs.Lookup("level").DefValue == "info")
+
+		assert(t, fs.Parse([]string{"-level", "loud"}) != nil)
+		assert(t, p == Error, p)
+	})
+}
@@ -0,0 +1,133 @@
+package level
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Filter describes a set of priorities. Unlike a threshold, which
+// admits every priority greater than or equal to a minimum, a filter
+// can admit specific levels (see Mask) or bounded ranges (see
+// Range.) Filters never admit Invalid.
+type Filter interface {
+	Allows(Priority) bool
+}
+
+// Mask is a set of specific priorities. The zero value is empty.
+type Mask [4]uint64
+
+// MaskOf returns a mask that admits the priorities.
+func MaskOf(ps ...Priority) Mask {
+	var m Mask
+	for _, p := range ps {
+		m = m.With(p)
+	}
+	return m
+}
+
+// ParseMask parses a comma separated list of priorities (as Parse)
+// into a mask, e.g. "notice,alert".
+func ParseMask(in string) (Mask, error) {
+	var m Mask
+	for name := range strings.SplitSeq(in, ",") {
+		p, err := Parse(name)
+		if err != nil {
+			return Mask{}, err
+		}
+		if p == Invalid {
+			return Mask{}, fmt.Errorf("masks cannot contain %q", name)
+		}
+		m = m.With(p)
+	}
+	return m, nil
+}
+
+// With returns a copy of the mask that also admits the priority.
+// Adding Invalid has no effect.
+func (m Mask) With(p Priority) Mask {
+	if p != Invalid {
+		m[p/64] |= 1 << (p % 64)
+	}
+	return m
+}
+
+// Without returns a copy of the mask that does not admit the
+// priority.
+func (m Mask) Without(p Priority) Mask { m[p/64] &^= 1 << (p % 64); return m }
+
+// Allows reports if the priority is in the mask.
+func (m Mask) Allows(p Priority) bool { return p != Invalid && m[p/64]&(1<<(p%64)) != 0 }
+
+// IsZero reports if the mask is empty.
+func (m Mask) IsZero() bool { return m == Mask{} }
+
+// Priorities returns the priorities in the mask, in ascending order.
+func (m Mask) Priorities() []Priority {
+	out := []Priority{}
+	for idx, word := range m {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			out = append(out, Priority(idx*64+bit))
+			word &^= 1 << bit
+		}
+	}
+	return out
+}
+
+// String returns the comma separated names of the priorities in the
+// mask, in ascending order, in the form accepted by ParseMask.
+func (m Mask) String() string {
+	ps := m.Priorities()
+	names := make([]string, len(ps))
+	for idx, p := range ps {
+		names[idx] = p.String()
+	}
+	return strings.Join(names, ",")
+}
+
+// Range is an inclusive range of priorities. Either end of the range
+// may be Invalid, which leaves that end of the range unbounded: a
+// Range with only a Min is equivalent to a threshold, and a Range
+// with only a Max is a ceiling.
+type Range struct {
+	Min Priority
+	Max Priority
+}
+
+// Allows reports if the priority is within the range.
+func (r Range) Allows(p Priority) bool {
+	switch {
+	case p == Invalid:
+		return false
+	case r.Min != Invalid && p < r.Min:
+		return false
+	case r.Max != Invalid && p > r.Max:
+		return false
+	default:
+		return true
+	}
+}
+
+// Mask returns a mask that admits the same priorities as the range.
+func (r Range) Mask() Mask {
+	var m Mask
+	for p := range 256 {
+		if r.Allows(Priority(p)) {
+			m = m.With(Priority(p))
+		}
+	}
+	return m
+}
+
+// String renders the range as "min-max", omitting unbounded ends.
+func (r Range) String() string {
+	var lower, upper string
+	if r.Min != Invalid {
+		lower = r.Min.String()
+	}
+	if r.Max != Invalid {
+		upper = r.Max.String()
+	}
+	return lower + "-" + upper
+}
@@ -0,0 +1,71 @@
+package level
+
+import (
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	var zero Mask
+	assert(t, zero.IsZero())
+	assert(t, zero.String() == "")
+
+	m := MaskOf(Notice, Alert, Invalid)
+	assert(t, !m.IsZero())
+	assert(t, m.Allows(Notice))
+	assert(t, m.Allows(Alert))
+	assert(t, !m.Allows(Emergency))
+	assert(t, !m.Allows(Warning))
+	assert(t, !m.Allows(Invalid))
+	assert(t, m.String() == "notice,alert", m)
+
+	m = m.With(255).With(1).Without(Notice)
+	assert(t, m.Allows(255) && m.Allows(1) && !m.Allows(Notice))
+	ps := m.Priorities()
+	assert(t, len(ps) == 3 && ps[0] == 1 && ps[1] == Alert && ps[2] == 255, ps)
+
+	t.Run("Parse", func(t *testing.T) {
+		parsed, err := ParseMask("notice, alert")
+		assert(t, err == nil, err)
+		assert(t, parsed == MaskOf(Notice, Alert))
+
+		parsed, err = ParseMask(MaskOf(Trace, 60, Emergency).String())
+		assert(t, err == nil, err)
+		assert(t, parsed == MaskOf(Trace, 60, Emergency), parsed)
+
+		for _, in := range []string{"", "notice,,alert", "notice,loud", "invalid"} {
+			_, err := ParseMask(in)
+			assert(t, err != nil, in)
+		}
+	})
+}
+
+func TestRange(t *testing.T) {
+	for _, tc := range []struct {
+		r       Range
+		allowed []Priority
+		denied  []Priority
+		str     string
+	}{
+		{r: Range{Min: Info, Max: Error}, allowed: []Priority{Info, Warning, Error}, denied: []Priority{Debug, Critical, Invalid}, str: "info-error"},
+		{r: Range{Min: Warning}, allowed: []Priority{Warning, Emergency, 255}, denied: []Priority{Info, Invalid}, str: "warning-"},
+		{r: Range{Max: Debug}, allowed: []Priority{1, Trace, Debug}, denied: []Priority{Info, Invalid}, str: "-debug"},
+		{r: Range{}, allowed: []Priority{1, Info, 255}, denied: []Priority{Invalid}, str: "-"},
+		{r: Range{Min: Error, Max: Info}, denied: []Priority{Info, Warning, Error}, str: "error-info"},
+	} {
+		t.Run(tc.str, func(t *testing.T) {
+			assert(t, tc.r.String() == tc.str, tc.r)
+			mask := tc.r.Mask()
+			for _, p := range tc.allowed {
+				assert(t, tc.r.Allows(p), p)
+				assert(t, mask.Allows(p), p)
+			}
+			for _, p := range tc.denied {
+				assert(t, !tc.r.Allows(p), p)
+				assert(t, !mask.Allows(p), p)
+			}
+		})
+	}
+
+	var _ Filter = Range{}
+	var _ Filter = Mask{}
+}
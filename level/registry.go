@@ -0,0 +1,186 @@
+package level
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Custom Levels
+//
+// In addition to the standard levels, applications may register
+// named priorities for the values between them (e.g. "audit" at 190
+// or "verbose" at 75.) Registered names are used by String and
+// FromString, and therefore by the marshaling of Priority values.
+// Senders for logging systems that only support the standard levels
+// (syslog, systemd, slog, zap, zerolog, etc.) map custom priorities
+// to the nearest standard level, using Standard.
+
+// standardLevels are the standard (named) priorities, in ascending
+// order.
+var standardLevels = []Priority{Trace, Debug, Info, Notice, Warning, Error, Critical, Alert, Emergency}
+
+var (
+	registryMu sync.Mutex
+	registry   atomic.Pointer[customLevels]
+)
+
+// customLevels is an immutable snapshot of the registry; Register
+// replaces the snapshot, so that lookups do not need to lock.
+type customLevels struct {
+	byName  map[string]Priority
+	byValue map[Priority]string
+}
+
+func customLevelName(p Priority) (string, bool) {
+	reg := registry.Load()
+	if reg == nil {
+		return "", false
+	}
+	name, ok := reg.byValue[p]
+	return name, ok
+}
+
+func customLevelValue(name string) (Priority, bool) {
+	reg := registry.Load()
+	if reg == nil {
+		return Invalid, false
+	}
+	p, ok := reg.byName[name]
+	return p, ok
+}
+
+// Register adds a named custom priority. Names are case insensitive,
+// and each name and each value may only be registered once. The
+// standard names and values, and Invalid, cannot be registered.
+func Register(name string, p Priority) error {
+	name = strings.TrimSpace(strings.ToLower(name))
+	switch {
+	case name == "":
+		return errors.New("custom levels must have a name")
+	case p == Invalid:
+		return fmt.Errorf("cannot register %q as the invalid priority", name)
+	case IsStandard(p):
+		return fmt.Errorf("cannot register %q as the standard priority %s", name, p)
+	case isNumeric(name):
+		return fmt.Errorf("cannot register the numeric name %q", name)
+	case name == "invalid" || IsStandard(FromString(name)):
+		return fmt.Errorf("cannot register the standard name %q", name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	next := &customLevels{byName: map[string]Priority{}, byValue: map[Priority]string{}}
+	if reg := registry.Load(); reg != nil {
+		next.byName = maps.Clone(reg.byName)
+		next.byValue = maps.Clone(reg.byValue)
+	}
+
+	if existing, ok := next.byName[name]; ok {
+		return fmt.Errorf("level %q is already registered as %d", name, existing)
+	}
+	if existing, ok := next.byValue[p]; ok {
+		return fmt.Errorf("priority %d is already registered as %q", p, existing)
+	}
+
+	next.byName[name] = p
+	next.byValue[p] = name
+	registry.Store(next)
+	return nil
+}
+
+func isNumeric(name string) bool {
+	_, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "level.priority<"), ">"))
+	return err == nil
+}
+
+// MustRegister is the same as Register, but panics if the level
+// cannot be registered. It's intended for use in package
+// initialization.
+func MustRegister(name string, p Priority) Priority {
+	if err := Register(name, p); err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Unregister removes a custom level, by name, and reports if the
+// level was registered.
+func Unregister(name string) bool {
+	name = strings.TrimSpace(strings.ToLower(name))
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	reg := registry.Load()
+	if reg == nil {
+		return false
+	}
+	p, ok := reg.byName[name]
+	if !ok {
+		return false
+	}
+
+	next := &customLevels{byName: maps.Clone(reg.byName), byValue: maps.Clone(reg.byValue)}
+	delete(next.byName, name)
+	delete(next.byValue, p)
+	registry.Store(next)
+	return true
+}
+
+// Registered returns an iterator over the custom levels, in priority
+// order.
+func Registered() iter.Seq2[string, Priority] {
+	reg := registry.Load()
+	return func(yield func(string, Priority) bool) {
+		if reg == nil {
+			return
+		}
+		for _, p := range slices.Sorted(maps.Keys(reg.byValue)) {
+			if !yield(reg.byValue[p], p) {
+				return
+			}
+		}
+	}
+}
+
+// IsStandard reports if the priority is one of the standard (named)
+// levels, excluding Invalid.
+func IsStandard(p Priority) bool {
+	_, ok := slices.BinarySearch(standardLevels, p)
+	return ok
+}
+
+// Standard returns the standard level nearest to the priority, which
+// makes it possible to map custom priorities onto logging systems
+// that only support the standard levels. Priorities that are exactly
+// between two standard levels map to the lower level. Standard
+// returns Invalid for Invalid.
+func Standard(p Priority) Priority {
+	if p == Invalid {
+		return Invalid
+	}
+
+	idx, ok := slices.BinarySearch(standardLevels, p)
+	switch {
+	case ok:
+		return p
+	case idx == 0:
+		return standardLevels[0]
+	case idx == len(standardLevels):
+		return standardLevels[len(standardLevels)-1]
+	}
+
+	lower, upper := standardLevels[idx-1], standardLevels[idx]
+	if p-lower <= upper-p {
+		return lower
+	}
+	return upper
+}
@@ -0,0 +1,84 @@
+package level
+
+import (
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Cleanup(func() {
+		Unregister("audit")
+		Unregister("verbose")
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		assert(t, Register("audit", 190) == nil)
+		assert(t, Register(" Verbose ", 75) == nil)
+
+		assert(t, Priority(190).String() == "audit", Priority(190))
+		assert(t, Priority(75).String() == "verbose", Priority(75))
+		assert(t, FromString("AUDIT") == 190)
+		assert(t, FromString("verbose") == 75)
+		assert(t, FromString(Priority(190).String()) == 190)
+
+		// numeric forms still work
+		assert(t, FromString("190") == 190)
+		assert(t, FromString("level.Priority<75>") == 75)
+		assert(t, Priority(76).String() == "level.Priority<76>")
+	})
+	t.Run("Conflicts", func(t *testing.T) {
+		for name, p := range map[string]Priority{
+			"audit":               191, // name in use
+			"other":               190, // value in use
+			"":                    80,
+			"loud":                Error,
+			"nothing":             Invalid,
+			"error":               176,
+			"invalid":             176,
+			"42":                  176,
+			"level.priority<176>": 176,
+		} {
+			assert(t, Register(name, p) != nil, name, p)
+		}
+	})
+	t.Run("MustRegister", func(t *testing.T) {
+		defer func() { assert(t, recover() != nil) }()
+		MustRegister("error", 180)
+	})
+	t.Run("Registered", func(t *testing.T) {
+		var names []string
+		for name := range Registered() {
+			names = append(names, name)
+		}
+		assert(t, len(names) == 2, names)
+		assert(t, names[0] == "verbose" && names[1] == "audit", names)
+	})
+	t.Run("Unregister", func(t *testing.T) {
+		assert(t, MustRegister("temporary", 60) == 60)
+		assert(t, Unregister("Temporary"))
+		assert(t, !Unregister("temporary"))
+		assert(t, FromString("temporary") == Invalid)
+		assert(t, Priority(60).String() == "level.Priority<60>")
+	})
+}
+
+func TestStandard(t *testing.T) {
+	for in, out := range map[Priority]Priority{
+		Invalid:   Invalid,
+		1:         Trace,
+		Trace:     Trace,
+		60:        Debug,
+		75:        Debug, // ties round down
+		76:        Info,
+		190:       Critical,
+		Emergency: Emergency,
+		255:       Emergency,
+	} {
+		assert(t, Standard(in) == out, in, Standard(in), out)
+	}
+
+	for i := range 256 {
+		p := Standard(Priority(i))
+		assert(t, p == Invalid || IsStandard(p), i)
+	}
+	assert(t, !IsStandard(Invalid))
+}
@@ -41,11 +41,10 @@
 package grip
 
 import (
-	"os"
-	"path/filepath"
-	"strings"
+	"context"
 
 	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/fun/erc"
 	"github.com/tychoish/grip/level"
 	"github.com/tychoish/grip/message"
 	"github.com/tychoish/grip/send"
@@ -55,21 +54,32 @@ var std Logger
 
 func init() { setupDefault() }
 
+// setupDefault configures the standard logger using the zero Config,
+// which writes to standard output at the Info level; use Configure or
+// ConfigureFromEnv to change this configuration. Named loggers share
+// the standard logger's sender, so once the standard logger exists,
+// setupDefault resets it in place rather than replacing it.
 func setupDefault() {
-	sender := send.MakeStdOutput()
-	if !strings.Contains(os.Args[0], "go-build") {
-		sender.SetName(filepath.Base(os.Args[0]))
-	} else {
-		sender.SetName("grip")
+	s := erc.Must(Config{}.Build())
+	if std.impl == nil {
+		std = NewLogger(s)
+		return
 	}
 
-	sender.SetPriority(level.Info)
+	std.SetThreshold(level.Invalid)
+	std.SetConverter(message.DefaultConverter())
+	std.SetSender(s)
+}
 
-	std = NewLogger(sender)
+// minimallist wrapper to make the atomic not panic because of the
+// interface; the inflight tracker makes it possible to drain the
+// sender when it's replaced (see SwapSender.)
+type sender struct {
+	send.Sender
+	*inflight
 }
 
-// minimallist wrapper to make the atomic not panic because of the interface
-type sender struct{ send.Sender }
+func makeSender(s send.Sender) sender { return sender{Sender: s, inflight: newInflight()} }
 
 type converter struct{ message.Converter }
 
@@ -77,9 +87,19 @@ type converter struct{ message.Converter }
 //
 // Package level functions mirror all methods on the Logger type to
 // access a "global" Logger instance in the grip package.
+//
+// In addition to the threshold of its sender, a Logger may carry its
+// own priority threshold (see SetThreshold), which is how named
+// loggers (see Named) override the level for a single component
+// while sharing a sender with the rest of the application.
 type Logger struct {
 	impl *adt.Atomic[sender]
 	conv *adt.Atomic[converter]
+	lvl  *adt.Atomic[level.Priority]
+	name string
+
+	fields  *fieldSet
+	sampler *send.Sampler
 }
 
 // NewLogger builds a new logging interface from a sender implementation.
@@ -90,21 +110,72 @@ func NewLogger(s send.Sender) Logger {
 // MakeLogger constructs a new sender with the specified converter function.
 func MakeLogger(s send.Sender, c message.Converter) Logger {
 	return Logger{
-		impl: adt.NewAtomic(sender{s}),
+		impl: adt.NewAtomic(makeSender(s)),
 		conv: adt.NewAtomic(converter{c}),
+		lvl:  adt.NewAtomic(level.Invalid),
+	}
+}
+
+// Clone creates a new Logger with the same message sender,
+// converter, name, threshold, fields, and sampler; however they are fully independent
+// loggers.
+func (g Logger) Clone() Logger {
+	out := MakeLogger(g.Sender(), g.conv.Get())
+	out.name = g.name
+	out.fields = g.fields
+	out.sampler = g.sampler
+	out.lvl.Set(g.lvl.Get())
+	return out
+}
+
+// SetThreshold sets a priority threshold for the logger that is
+// checked before messages are passed to the sender. Messages with a
+// priority below the threshold are dropped; the sender's own priority
+// continues to apply to messages that pass. Use level.Invalid to
+// clear the threshold. The thresholds of named loggers are managed by
+// the level rules, which lower the sender's priority when needed (see
+// SetLevelRule); named loggers that no rule matches follow the
+// threshold of the standard logger.
+func (g Logger) SetThreshold(p level.Priority) {
+	g.lvl.Set(p)
+	if g.lvl == std.lvl {
+		named.reapply()
 	}
 }
 
-// Clone creates a new Logger with the same message sender and
-// converter; however they are fully independent loggers.
-func (g Logger) Clone() Logger                    { return MakeLogger(g.Sender(), g.conv.Get()) }
-func (g Logger) Build() *message.Builder          { return message.NewBuilder(g.Sender().Send, g.conv.Get()) }
+// Threshold reports the effective threshold of the logger: the
+// higher of the logger's own threshold, if one is set, and the
+// sender's priority. As both thresholds apply, a logger threshold
+// below the sender's priority has no effect.
+func (g Logger) Threshold() level.Priority {
+	return max(g.lvl.Get(), g.Sender().Priority())
+}
+
+// SetSender sets the logger's sender. Because named loggers share the
+// standard logger's sender, setting the sender of the standard logger
+// or of a named logger applies the level rules (see SetLevelRule) to
+// the new sender.
+func (g Logger) SetSender(s send.Sender) {
+	g.impl.Set(makeSender(s))
+	g.sharedSenderChanged()
+}
+
+// sharedSenderChanged applies the level rules again if the logger
+// shares the standard logger's sender.
+func (g Logger) sharedSenderChanged() {
+	if g.impl == std.impl {
+		named.reapply()
+	}
+}
+
+// Name returns the dotted name of a named logger, or an empty string
+// for loggers that were not produced by Named.
+func (g Logger) Name() string { return g.name }
+
+func (g Logger) Build() *message.Builder          { return message.NewBuilder(g.Send, g.conv.Get()) }
 func (g Logger) Sender() send.Sender              { return g.impl.Get().Sender }
 func (g Logger) Convert(m any) message.Composer   { return g.conv.Get().Convert(m) }
-func (g Logger) SetSender(s send.Sender)          { g.impl.Set(sender{s}) }
 func (g Logger) SetConverter(m message.Converter) { g.conv.Set(converter{m}) }
-func (g Logger) Send(m message.Composer)          { g.Sender().Send(m) }
-func (g Logger) Log(l level.Priority, m any)      { g.Send(g.make(l, m)) }
 func (g Logger) EmergencyPanic(m any)             { g.sendPanic(level.Emergency, m) }
 func (g Logger) EmergencyFatal(m any)             { g.sendFatal(level.Emergency, m) }
 func (g Logger) Emergency(m any)                  { g.Log(level.Emergency, m) }
@@ -117,6 +188,25 @@ func (g Logger) Info(m any)                       { g.Log(level.Info, m) }
 func (g Logger) Debug(m any)                      { g.Log(level.Debug, m) }
 func (g Logger) Trace(m any)                      { g.Log(level.Trace, m) }
 
+// The *Ctx methods are equivalent to their counterparts, except that
+// the context is passed to senders that implement
+// send.ContextSender, and messages are annotated with the fields
+// attached to the context (see WithFields). Messages are sent even
+// if the context is canceled, so that errors about canceled
+// operations are logged; senders decide how to apply the context's
+// deadline. A nil context is equivalent to calling the counterpart.
+// The *Ctx methods have the same call depth as their counterparts,
+// so call site formatters need no adjustment.
+func (g Logger) EmergencyCtx(ctx context.Context, m any) { g.LogCtx(ctx, level.Emergency, m) }
+func (g Logger) AlertCtx(ctx context.Context, m any)     { g.LogCtx(ctx, level.Alert, m) }
+func (g Logger) CriticalCtx(ctx context.Context, m any)  { g.LogCtx(ctx, level.Critical, m) }
+func (g Logger) ErrorCtx(ctx context.Context, m any)     { g.LogCtx(ctx, level.Error, m) }
+func (g Logger) WarningCtx(ctx context.Context, m any)   { g.LogCtx(ctx, level.Warning, m) }
+func (g Logger) NoticeCtx(ctx context.Context, m any)    { g.LogCtx(ctx, level.Notice, m) }
+func (g Logger) InfoCtx(ctx context.Context, m any)      { g.LogCtx(ctx, level.Info, m) }
+func (g Logger) DebugCtx(ctx context.Context, m any)     { g.LogCtx(ctx, level.Debug, m) }
+func (g Logger) TraceCtx(ctx context.Context, m any)     { g.LogCtx(ctx, level.Trace, m) }
+
 func Build() *message.Builder                        { return std.Build() }
 func BuildKV() *message.KV                           { return message.NewKV() }
 func B() *message.Builder                            { return std.Build() }
@@ -133,6 +223,7 @@ func SetSender(s send.Sender)          { std.SetSender(s) }
 func SetConverter(c message.Converter) { std.SetConverter(c) }
 func Send(m message.Composer)          { std.Send(m) }
 func Log(l level.Priority, msg any)    { std.Log(l, msg) }
+func Enabled(l level.Priority) bool    { return std.Enabled(l) }
 func EmergencyPanic(msg any)           { std.EmergencyPanic(msg) }
 func EmergencyFatal(msg any)           { std.EmergencyFatal(msg) }
 func Emergency(msg any)                { std.Emergency(msg) }
@@ -145,34 +236,153 @@ func Info(msg any)                     { std.Info(msg) }
 func Debug(msg any)                    { std.Debug(msg) }
 func Trace(msg any)                    { std.Trace(msg) }
 
+func SendCtx(ctx context.Context, m message.Composer)       { std.SendCtx(ctx, m) }
+func LogCtx(ctx context.Context, l level.Priority, msg any) { std.LogCtx(ctx, l, msg) }
+func EmergencyCtx(ctx context.Context, msg any)             { std.EmergencyCtx(ctx, msg) }
+func AlertCtx(ctx context.Context, msg any)                 { std.AlertCtx(ctx, msg) }
+func CriticalCtx(ctx context.Context, msg any)              { std.CriticalCtx(ctx, msg) }
+func ErrorCtx(ctx context.Context, msg any)                 { std.ErrorCtx(ctx, msg) }
+func WarningCtx(ctx context.Context, msg any)               { std.WarningCtx(ctx, msg) }
+func NoticeCtx(ctx context.Context, msg any)                { std.NoticeCtx(ctx, msg) }
+func InfoCtx(ctx context.Context, msg any)                  { std.InfoCtx(ctx, msg) }
+func DebugCtx(ctx context.Context, msg any)                 { std.DebugCtx(ctx, msg) }
+func TraceCtx(ctx context.Context, msg any)                 { std.TraceCtx(ctx, msg) }
+
 // implementation
 
 ///////////////////////////////////
 //
 // method implementation
 
+// Enabled reports if the logger would send a message at the given
+// priority: the priority must pass both the logger's threshold and
+// the sender (see send.Allows.) Enabled does not allocate, and makes it
+// possible to avoid constructing expensive messages that would be
+// dropped. Messages that pass may still be dropped if they are not
+// loggable (e.g. empty messages.)
+func (g Logger) Enabled(p level.Priority) bool {
+	if t := g.lvl.Get(); p == level.Invalid || (t != level.Invalid && p < t) {
+		return false
+	}
+	return send.Allows(g.impl.Get().Sender, p)
+}
+
+// Log converts and sends the message at the given priority. Values
+// that are not already Composers are not converted if the logger
+// isn't Enabled for the priority, so calls that would be dropped do
+// not allocate. Composers always have their priority set and are
+// passed to Send.
+func (g Logger) Log(l level.Priority, m any) {
+	if g.admitsValue(l, m) {
+		g.Send(g.make(l, m))
+	}
+}
+
+// LogCtx is the context-aware equivalent of Log.
+func (g Logger) LogCtx(ctx context.Context, l level.Priority, m any) {
+	if g.admitsValue(l, m) {
+		g.SendCtx(ctx, g.make(l, m))
+	}
+}
+
+func (g Logger) admitsValue(l level.Priority, m any) bool {
+	if _, ok := m.(message.Composer); ok {
+		return true
+	}
+	return g.Enabled(l)
+}
+
 func (g Logger) make(l level.Priority, in any) message.Composer {
 	m := g.Convert(in)
 	m.SetPriority(l)
 	return m
 }
 
-func (g Logger) ms(l level.Priority, i any) (message.Composer, send.Sender) {
-	return g.make(l, i), g.Sender()
+// Send delivers the message to the sender, if it passes the logger's
+// threshold. Loggers with fields (e.g. loggers resolved from a
+// context with fields; see WithFields) annotate loggable messages
+// with their fields before sending them.
+func (g Logger) Send(m message.Composer) {
+	s := g.acquire()
+	defer s.release()
+
+	if g.prepare(s.Sender, m) {
+		s.Send(m)
+	}
 }
 
-func (g Logger) sendPanic(l level.Priority, in any) {
-	if m, s := g.ms(l, in); send.ShouldLog(s, m) {
+// admits checks the message against the logger's own threshold, if
+// set; the sender is responsible for its own threshold.
+func (g Logger) admits(m message.Composer) bool {
+	t := g.lvl.Get()
+	return t == level.Invalid || (m != nil && m.Priority() >= t)
+}
+
+// SendCtx is the context-aware equivalent of Send. The sender is
+// called directly, rather than through send.SendContext, to keep the
+// call depth the same as Send's.
+func (g Logger) SendCtx(ctx context.Context, m message.Composer) {
+	g = g.withFields(contextFields(ctx))
+	s := g.acquire()
+	defer s.release()
+
+	if !g.prepare(s.Sender, m) {
+		return
+	}
+	if cs, ok := s.Sender.(send.ContextSender); ok && ctx != nil {
+		cs.SendContext(ctx, m)
+	} else {
 		s.Send(m)
+	}
+}
+
+// prepare reports if the message should be sent to the sender,
+// annotating it with the logger's fields if so. Senders perform
+// their own threshold check, so prepare only calls ShouldLog when it
+// must annotate or sample the message.
+func (g Logger) prepare(s send.Sender, m message.Composer) bool {
+	switch {
+	case !g.admits(m):
+		return false
+	case g.fields == nil && g.sampler == nil:
+		return true
+	case !send.ShouldLog(s, m):
+		return false
+	case g.sampler != nil && !g.sampler.Allow(m):
+		return false
+	default:
+		g.fields.annotate(m)
+		return true
+	}
+}
+
+func (g Logger) sendPanic(l level.Priority, in any) {
+	if m, s := g.sendChecked(l, in); m != nil {
+		exit.panic(s, m)
 		panic(m.String())
 	}
 }
 
+// sendChecked sends the message, returning it and the sender, or nil
+// if the message was not loggable. Unlike Send, the message is only
+// annotated and sent if it passes the sender's threshold, as the
+// panic and fatal paths must not panic or exit for messages that
+// aren't logged.
+func (g Logger) sendChecked(l level.Priority, in any) (message.Composer, send.Sender) {
+	s := g.acquire()
+	defer s.release()
+
+	if m := g.make(l, in); g.prepare(s.Sender, m) && send.ShouldLog(s, m) {
+		s.Send(m)
+		return m, s.Sender
+	}
+	return nil, nil
+}
+
 func (g Logger) sendFatal(l level.Priority, in any) {
 	// the Send method in the Sender interface will perform this
 	// check but to add fatal methods we need to do this here.
-	if m, s := g.ms(l, in); send.ShouldLog(s, m) {
-		s.Send(m)
-		os.Exit(1)
+	if m, s := g.sendChecked(l, in); m != nil {
+		exit.fatal(s, m)
 	}
 }
@@ -1,10 +1,13 @@
 package grip
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
 	"testing"
 
 	"github.com/tychoish/fun/assert/check"
@@ -341,3 +344,154 @@ func TestSendFatalExits(t *testing.T) {
 		grip.sendFatal(0, message.Convert("hello world"))
 	})
 }
+
+func TestEnabled(t *testing.T) {
+	sink := send.MakeInternal()
+	sink.SetPriority(level.Info)
+	logger := NewLogger(sink)
+
+	check.True(t, logger.Enabled(level.Info))
+	check.True(t, logger.Enabled(level.Emergency))
+	check.True(t, !logger.Enabled(level.Debug))
+	check.True(t, !logger.Enabled(level.Invalid))
+
+	logger.SetThreshold(level.Warning)
+	check.True(t, !logger.Enabled(level.Info))
+	check.True(t, logger.Enabled(level.Warning))
+
+	t.Run("SkipsConversion", func(t *testing.T) {
+		converted := 0
+		logger := MakeLogger(sink, message.ConverterFunc(func(m any) (message.Composer, bool) {
+			converted++
+			return message.MakeString(fmt.Sprint(m)), true
+		}))
+
+		logger.Debug("dropped")
+		logger.DebugCtx(t.Context(), "dropped")
+		check.Equal(t, converted, 0)
+		check.Equal(t, sink.Len(), 0)
+
+		logger.Info("sent")
+		check.Equal(t, converted, 1)
+		check.Equal(t, sink.Len(), 1)
+	})
+}
+
+func TestEnabledMasked(t *testing.T) {
+	sink := send.MakeInternal()
+	sink.SetPriority(level.Trace)
+	masked := send.MakeMasked(sink, level.MaskOf(level.Notice, level.Alert))
+	// the masked sender's priority is below every level in the
+	// mask, so Enabled must use the filter, not the priority.
+	masked.SetPriority(level.Trace)
+	logger := NewLogger(masked)
+
+	if !logger.Enabled(level.Notice) || !logger.Enabled(level.Alert) {
+		t.Error("masked levels should be enabled")
+	}
+	if logger.Enabled(level.Debug) || logger.Enabled(level.Error) || logger.Enabled(level.Emergency) {
+		t.Error("levels outside of the mask should not be enabled")
+	}
+
+	// Enabled agrees with delivery at every level.
+	for _, p := range []level.Priority{level.Trace, level.Debug, level.Info, level.Notice, level.Warning, level.Error, level.Critical, level.Alert, level.Emergency} {
+		before := sink.Len()
+		logger.Log(p, "message")
+		if delivered := sink.Len() > before; delivered != logger.Enabled(p) {
+			t.Errorf("level %s: enabled=%t delivered=%t", p, logger.Enabled(p), delivered)
+		}
+	}
+
+	logger.SetThreshold(level.Warning)
+	check.True(t, !logger.Enabled(level.Notice))
+	check.True(t, logger.Enabled(level.Alert))
+}
+
+func TestCallSiteDepth(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sender := send.MakeWriter(buf)
+	sender.SetPriority(level.Info)
+	sender.SetFormatter(send.MakeCallSiteFormatter(DefaultCallSiteDepth))
+	logger := NewLogger(sender)
+
+	msg := func() message.Composer {
+		m := message.MakeString("hello")
+		m.SetPriority(level.Info)
+		return m
+	}
+	expect := func(t *testing.T, line int) {
+		t.Helper()
+		check.Substring(t, buf.String(), fmt.Sprintf("/logger_test.go:%d]", line))
+		buf.Reset()
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello")
+	expect(t, line+1)
+
+	_, _, line, _ = runtime.Caller(0)
+	logger.InfoCtx(t.Context(), "hello")
+	expect(t, line+1)
+
+	// Send and SendCtx are two frames closer to the sender.
+	sender.SetFormatter(send.MakeCallSiteFormatter(DefaultCallSiteDepth - 2))
+	_, _, line, _ = runtime.Caller(0)
+	logger.Send(msg())
+	expect(t, line+1)
+
+	_, _, line, _ = runtime.Caller(0)
+	logger.SendCtx(t.Context(), msg())
+	expect(t, line+1)
+}
+
+func TestCanceledContext(t *testing.T) {
+	sender := send.MakeInternal()
+	sender.SetPriority(level.Info)
+	logger := NewLogger(sender)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	logger.ErrorCtx(ctx, ctx.Err())
+	check.Equal(t, sender.GetMessage().Rendered, context.Canceled.Error())
+	logger.SendCtx(ctx, message.MakeString("canceled"))
+	check.Equal(t, sender.GetMessage().Rendered, "canceled")
+
+	var tracking contextTrackingSender
+	tracking.SetPriority(level.Info)
+	logger = NewLogger(&tracking)
+	logger.InfoCtx(nil, "nil")                     //nolint:staticcheck
+	logger.SendCtx(nil, message.MakeString("nil")) //nolint:staticcheck
+	check.Equal(t, tracking.sent, 2)
+	check.Equal(t, tracking.withContext, 0)
+
+	logger.InfoCtx(ctx, "canceled")
+	check.Equal(t, tracking.withContext, 1)
+}
+
+// contextTrackingSender counts the messages sent with and without a
+// context.
+type contextTrackingSender struct {
+	send.Base
+	sent        int
+	withContext int
+}
+
+func (s *contextTrackingSender) Send(message.Composer) { s.sent++ }
+func (s *contextTrackingSender) SendContext(context.Context, message.Composer) {
+	s.withContext++
+}
+
+func TestStructConverter(t *testing.T) {
+	sender := send.MakeInternal()
+	sender.SetPriority(level.Info)
+	logger := MakeLogger(sender, message.StructConverter())
+
+	logger.Info(struct {
+		User  string `json:"user"`
+		Token string `grip:"redact"`
+	}{User: "jo", Token: "abc"})
+
+	if out := sender.GetMessage().Rendered; out != "user='jo' Token='[REDACTED]'" {
+		t.Error(out)
+	}
+}
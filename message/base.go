@@ -30,8 +30,12 @@ func init() {
 // aspects of a message.Composer. Additionally the Collect() method
 // collects some simple metadata, that may be useful for some more
 // structured logging applications.
+//
+// The Level field is a level.Number, so that it encodes as the
+// number of the priority in JSON (e.g. "level":100), and decodes from
+// either numbers or names.
 type Base struct {
-	Level                 level.Priority             `bson:"level,omitempty" json:"level,omitempty" yaml:"level,omitempty"`
+	Level                 level.Number               `bson:"level,omitempty" json:"level,omitempty" yaml:"level,omitempty"`
 	Pid                   int                        `bson:"pid,omitempty" json:"pid,omitempty" yaml:"pid,omitempty"`
 	Process               string                     `bson:"proc,omitempty" json:"proc,omitempty" yaml:"proc,omitempty"`
 	Host                  string                     `bson:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
@@ -68,7 +72,7 @@ func (b *Base) SetOption(opts ...Option) {
 // IsZero returns true when Base is nil or it is non-nil and none of
 // its fields are set.
 func (b *Base) IsZero() bool {
-	return b == nil || b.Level == level.Invalid && b.Host == "" && b.Time.IsZero() && b.Process == "" && b.Pid == 0
+	return b == nil || level.Priority(b.Level) == level.Invalid && b.Host == "" && b.Time.IsZero() && b.Process == "" && b.Pid == 0
 }
 
 // Collect records the time, process name, and hostname. Useful in the
@@ -84,8 +88,24 @@ func (b *Base) Collect() {
 	b.Pid = pidCache.Resolve()
 }
 
+// Timestamp returns the time recorded by Collect, which is the zero
+// time unless the message collects info (see OptionCollectInfo.)
+func (b *Base) Timestamp() time.Time { b.Collect(); return b.Time }
+
+// Timestamp returns the time of the message for formatters: the time
+// recorded by the message (see Base.Timestamp), if any, and the
+// current time otherwise.
+func Timestamp(m Composer) time.Time {
+	if tm, ok := m.(interface{ Timestamp() time.Time }); ok {
+		if ts := tm.Timestamp(); !ts.IsZero() {
+			return ts
+		}
+	}
+	return time.Now()
+}
+
 // Priority returns the configured priority of the message.
-func (b *Base) Priority() level.Priority { return b.Level }
+func (b *Base) Priority() level.Priority { return level.Priority(b.Level) }
 
 // Structured returns true if there are any annotations. Otherwise
 
@@ -94,7 +114,7 @@ func (b *Base) Structured() bool { return b.Context.Len() >= 1 }
 
 // SetPriority allows you to configure the priority of the
 // message. Returns an error if the priority is not valid.
-func (b *Base) SetPriority(l level.Priority) { b.Level = l }
+func (b *Base) SetPriority(l level.Priority) { b.Level = level.Number(l) }
 
 // Annotate makes it possible for callers and senders to add
 // structured data to a message. This may be overridden for some
@@ -102,3 +122,5 @@ func (b *Base) SetPriority(l level.Priority) { b.Level = l }
 func (b *Base) Annotate(key string, value any) {
 	b.Context.Set(key, value)
 }
+
+func (b *Base) annotations() *dt.OrderedMap[string, any] { return &b.Context }
@@ -1,7 +1,11 @@
 package message
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/tychoish/grip/level"
 )
 
 func TestCollectWorksWithUnsetPids(t *testing.T) {
@@ -85,3 +89,23 @@ func TestAnnotateMultipleValues(t *testing.T) {
 		t.Fatal("should be structured")
 	}
 }
+
+func TestBaseLevelJSON(t *testing.T) {
+	base := &Base{}
+	base.SetPriority(level.Info)
+	out, err := json.Marshal(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"level":100`) {
+		t.Error(string(out))
+	}
+
+	// documents with named levels also decode
+	for _, doc := range []string{`{"level":100}`, `{"level":"info"}`} {
+		var base Base
+		if err := json.Unmarshal([]byte(doc), &base); err != nil || base.Priority() != level.Info {
+			t.Error(doc, base.Level, err)
+		}
+	}
+}
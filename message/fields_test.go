@@ -19,7 +19,7 @@ func TestFieldsLevelMutability(t *testing.T) {
 		t.Error("elements shold be equal")
 	}
 
-	if level.Error != r.Get("meta").(*Base).Level {
+	if level.Error != r.Get("meta").(*Base).Priority() {
 		t.Error("elements shold be equal")
 	}
 
@@ -31,7 +31,7 @@ func TestFieldsLevelMutability(t *testing.T) {
 	if level.Info != c.Priority() {
 		t.Error("elements shold be equal")
 	}
-	if level.Info != r.Get("meta").(*Base).Level {
+	if level.Info != r.Get("meta").(*Base).Priority() {
 		t.Error("elements shold be equal")
 	}
 }
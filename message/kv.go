@@ -54,6 +54,10 @@ func vtoany[V any](seq iter.Seq2[string, V]) iter.Seq2[string, any] {
 	return irt.Convert2(seq, func(key string, value V) (string, any) { return key, value })
 }
 
+// Iterator returns an iterator over the key/value pairs in the
+// message, in order.
+func (p *KV) Iterator() iter.Seq2[string, any] { return p.kvs.Iterator() }
+
 func (p *KV) Annotate(key string, value any) { p.kvs.Set(key, value) }
 func (p *KV) Loggable() bool                 { return !p.suppress && p.kvs.Len() > 0 }
 func (p *KV) SetOption(opts ...Option)       { p.core.SetOption(opts...) }
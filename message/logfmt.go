@@ -0,0 +1,139 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tychoish/grip/level"
+)
+
+// ParseLogfmt parses a logfmt line, as in:
+//
+//	level=warning msg="disk almost full" path=/var used=91%
+//
+// into a KV message with one pair for each key in the line, in
+// order. Values are strings; quoted values may use the escape
+// sequences \", \\, \n, \r, \t, and \uXXXX. Keys without values (as
+// in "retry") have the value true. When the line has a "level" key
+// that names a priority (see level.Parse) the key sets the priority
+// of the message rather than becoming a field.
+//
+// Lines without any key=value pairs are not logfmt: ParseLogfmt
+// returns them as a message with the (trimmed) line as the "msg"
+// field (message.FieldsMsgName), rather than as a series of keys.
+//
+// ParseLogfmt returns an error for malformed lines, such as lines with
+// unterminated quotes or values without keys.
+func ParseLogfmt(line string) (*KV, error) {
+	kv := NewKV()
+	rest := strings.TrimSpace(line)
+	if rest != "" && !strings.ContainsRune(rest, '=') {
+		return kv.KV(FieldsMsgName, rest), nil
+	}
+
+	for rest != "" {
+		idx := strings.IndexAny(rest, "= \t")
+		if idx == 0 {
+			return nil, fmt.Errorf("logfmt: value without a key at offset %d", len(line)-len(rest))
+		}
+
+		var key string
+		if idx < 0 {
+			key, rest = rest, ""
+		} else {
+			key, rest = rest[:idx], rest[idx:]
+		}
+		if !utf8.ValidString(key) || strings.ContainsRune(key, '"') {
+			return nil, fmt.Errorf("logfmt: invalid key %q", key)
+		}
+
+		if rest == "" || rest[0] != '=' {
+			kv.KV(key, true)
+			rest = strings.TrimLeft(rest, " \t")
+			continue
+		}
+		rest = rest[1:]
+
+		var (
+			value string
+			err   error
+		)
+		switch {
+		case strings.HasPrefix(rest, `"`):
+			value, rest, err = unquoteLogfmt(rest)
+			if err != nil {
+				return nil, fmt.Errorf("logfmt: value for %q: %w", key, err)
+			}
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				return nil, fmt.Errorf("logfmt: unexpected %q after the value for %q", rest[0], key)
+			}
+		default:
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+			if strings.ContainsAny(value, `"=`) {
+				return nil, fmt.Errorf("logfmt: unquoted value for %q contains reserved characters", key)
+			}
+		}
+
+		if key == "level" {
+			if p, err := level.Parse(value); err == nil && p != level.Invalid {
+				kv.SetPriority(p)
+				rest = strings.TrimLeft(rest, " \t")
+				continue
+			}
+		}
+
+		kv.KV(key, value)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+
+	return kv, nil
+}
+
+// unquoteLogfmt reads the quoted string at the beginning of the input
+// and returns the unquoted value and the remainder of the input.
+func unquoteLogfmt(in string) (string, string, error) {
+	var buf strings.Builder
+	for idx := 1; idx < len(in); idx++ {
+		switch ch := in[idx]; ch {
+		case '"':
+			return buf.String(), in[idx+1:], nil
+		case '\\':
+			idx++
+			if idx >= len(in) {
+				return "", "", errors.New("unterminated escape sequence")
+			}
+			switch esc := in[idx]; esc {
+			case '"', '\\', '/':
+				buf.WriteByte(esc)
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'u':
+				if idx+4 >= len(in) {
+					return "", "", errors.New("short unicode escape sequence")
+				}
+				r, err := strconv.ParseUint(in[idx+1:idx+5], 16, 16)
+				if err != nil {
+					return "", "", fmt.Errorf("invalid unicode escape sequence %q", in[idx-1:idx+5])
+				}
+				buf.WriteRune(rune(r))
+				idx += 4
+			default:
+				return "", "", fmt.Errorf("invalid escape sequence %q", in[idx-1:idx+1])
+			}
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return "", "", errors.New("unterminated quoted value")
+}
@@ -0,0 +1,81 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tychoish/fun/irt"
+	"github.com/tychoish/grip/level"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	t.Run("Pairs", func(t *testing.T) {
+		kv, err := ParseLogfmt(`level=warning msg="disk almost full" path=/var used=91% retry  empty="" esc="a \"b\"\n\tc\\ é"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kv.Priority() != level.Warning {
+			t.Error(kv.Priority())
+		}
+
+		pairs := irt.Collect(irt.KVjoin(kv.Iterator()))
+		expected := []irt.KV[string, any]{
+			irt.MakeKV[string, any]("msg", "disk almost full"),
+			irt.MakeKV[string, any]("path", "/var"),
+			irt.MakeKV[string, any]("used", "91%"),
+			irt.MakeKV[string, any]("retry", true),
+			irt.MakeKV[string, any]("empty", ""),
+			irt.MakeKV[string, any]("esc", "a \"b\"\n\tc\\ é"),
+		}
+		if len(pairs) != len(expected) {
+			t.Fatal(pairs)
+		}
+		for idx := range expected {
+			if pairs[idx] != expected[idx] {
+				t.Errorf("%d: got %v, expected %v", idx, pairs[idx], expected[idx])
+			}
+		}
+	})
+	t.Run("Level", func(t *testing.T) {
+		kv, err := ParseLogfmt("level=loud a=b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kv.Priority() != level.Invalid || kv.String() != "level='loud' a='b'" {
+			t.Error("unknown levels should be fields", kv.String())
+		}
+
+		kv, err = ParseLogfmt("")
+		if err != nil || kv.Loggable() {
+			t.Error("empty lines should produce empty messages")
+		}
+	})
+	t.Run("Prose", func(t *testing.T) {
+		for _, line := range []string{"connection refused", "  retry  ", `he said "hi"`} {
+			kv, err := ParseLogfmt(line)
+			if err != nil {
+				t.Fatal(line, err)
+			}
+			pairs := irt.Collect(irt.KVjoin(kv.Iterator()))
+			if len(pairs) != 1 || pairs[0] != irt.MakeKV[string, any]("msg", strings.TrimSpace(line)) {
+				t.Errorf("%q: %v", line, pairs)
+			}
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		for _, line := range []string{
+			`=value`,
+			`a="unterminated`,
+			`a="bad \q escape"`,
+			`a="\u00"`,
+			`a="x"y`,
+			`a=b=c`,
+			`a=b"c`,
+			`"key"=value`,
+		} {
+			if _, err := ParseLogfmt(line); err == nil {
+				t.Errorf("%q should not parse", line)
+			}
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package message
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tychoish/fun/dt"
+)
+
+// DefaultRedactionMask is the value that replaces redacted data when
+// the Redactor does not specify a mask.
+const DefaultRedactionMask = "[REDACTED]"
+
+// Redactable values control their own redaction: when a Redactor
+// encounters a Redactable value, it uses the value returned by
+// Redact in place of the value, regardless of the field name.
+type Redactable interface {
+	Redact() any
+}
+
+var (
+	// CreditCardPattern matches 16 digit card numbers and 15
+	// digit (American Express) card numbers, optionally separated
+	// into groups by spaces or dashes.
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{4}|3[47]\d{2}[ -]?\d{6}[ -]?\d{5})\b`)
+	// BearerTokenPattern matches bearer tokens, as in HTTP
+	// Authorization headers.
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/-]+=*`)
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+)
+
+// SensitiveKeys are glob patterns for field names that commonly hold
+// credentials.
+var SensitiveKeys = []string{
+	"*password*", "*passwd*", "*secret*", "*token*",
+	"*api_key*", "*apikey*", "authorization", "cookie", "set-cookie",
+}
+
+// Redactor describes the data to remove from messages. Values are
+// redacted when their field name matches one of the Keys or
+// KeyPatterns, when they implement Redactable, or, for strings, where
+// they match one of the Values patterns.
+//
+// Redactors are safe for concurrent use as long as their fields are
+// not modified.
+type Redactor struct {
+	// Keys are glob patterns (see path.Match) that are matched,
+	// case insensitively, against field names. The values of
+	// matching fields are replaced with the mask.
+	Keys []string
+	// KeyPatterns are regular expressions that are matched
+	// against field names.
+	KeyPatterns []*regexp.Regexp
+	// Values are regular expressions that are matched against
+	// string values, and against the string form of messages
+	// that do not have fields. Only the matching portions of the
+	// strings are replaced with the mask.
+	Values []*regexp.Regexp
+	// Mask replaces redacted data. Defaults to
+	// DefaultRedactionMask.
+	Mask string
+}
+
+// DefaultRedactor returns a Redactor that masks the values of the
+// SensitiveKeys, as well as credit card numbers, bearer tokens, and
+// email addresses.
+func DefaultRedactor() *Redactor {
+	return &Redactor{
+		Keys:   slices.Clone(SensitiveKeys),
+		Values: []*regexp.Regexp{CreditCardPattern, BearerTokenPattern, EmailPattern},
+	}
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return DefaultRedactionMask
+	}
+	return r.Mask
+}
+
+// MatchesKey reports if the values of fields with this name are
+// redacted.
+func (r *Redactor) MatchesKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, pattern := range r.Keys {
+		if ok, _ := path.Match(strings.ToLower(pattern), lower); ok {
+			return true
+		}
+	}
+	for _, pattern := range r.KeyPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text replaces the portions of the string that match the Values
+// patterns with the mask.
+func (r *Redactor) Text(in string) string {
+	for _, pattern := range r.Values {
+		in = pattern.ReplaceAllLiteralString(in, r.mask())
+	}
+	return in
+}
+
+// Field returns the redacted form of the value of a field.
+func (r *Redactor) Field(key string, value any) any {
+	if r.MatchesKey(key) {
+		return r.mask()
+	}
+	return r.Value(value)
+}
+
+// Value returns the redacted form of a value. Value redacts strings,
+// Redactable values, and the fields of maps (including Fields and
+// *dt.OrderedMap[string, any]) and slices, recursively. Other maps
+// and structs (but not errors) are converted to map[string]any
+// documents, using their JSON form, so that their fields are
+// redacted by name; other values are returned unchanged. Maps and
+// slices are copied rather than modified.
+func (r *Redactor) Value(value any) any {
+	switch val := value.(type) {
+	case Redactable:
+		return val.Redact()
+	case nil, error, time.Time:
+		return value
+	case string:
+		return r.Text(val)
+	case StackTrace:
+		return StackTrace{Frames: val.Frames, Context: r.Value(val.Context)}
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, v := range val {
+			out[k] = r.Field(k, v)
+		}
+		return out
+	case Fields:
+		out := make(Fields, len(val))
+		for k, v := range val {
+			out[k] = r.Field(k, v)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, v := range val {
+			if r.MatchesKey(k) {
+				out[k] = r.mask()
+			} else {
+				out[k] = r.Text(v)
+			}
+		}
+		return out
+	case *dt.OrderedMap[string, any]:
+		out := &dt.OrderedMap[string, any]{}
+		for k, v := range val.Iterator() {
+			out.Set(k, r.Field(k, v))
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for idx := range val {
+			out[idx] = r.Value(val[idx])
+		}
+		return out
+	case []string:
+		out := make([]string, len(val))
+		for idx := range val {
+			out[idx] = r.Text(val[idx])
+		}
+		return out
+	default:
+		if doc, ok := jsonDocument(value); ok {
+			return r.Value(doc)
+		}
+		return value
+	}
+}
+
+// jsonDocument converts maps and structs to documents using their
+// JSON form, which is the form that most formatters render.
+func jsonDocument(value any) (map[string]any, bool) {
+	if kind := reflect.Indirect(reflect.ValueOf(value)).Kind(); kind != reflect.Struct && kind != reflect.Map {
+		return nil, false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// kv returns a redacted copy of the KV message.
+func (r *Redactor) kv(in *KV) *KV {
+	out := &KV{suppress: in.suppress}
+	out.core.Level = in.core.Level
+	out.core.Pid = in.core.Pid
+	out.core.Process = in.core.Process
+	out.core.Host = in.core.Host
+	out.core.Time = in.core.Time
+	out.core.CollectInfo = in.core.CollectInfo
+	out.core.IncludeMetadata = in.core.IncludeMetadata
+	out.core.MessageIsSpecial = in.core.MessageIsSpecial
+	out.core.SortComponents = in.core.SortComponents
+	out.core.RenderExtendedStrings = in.core.RenderExtendedStrings
+
+	for k, v := range in.kvs.Iterator() {
+		if _, ok := skippedFields[k]; ok {
+			continue
+		}
+		out.kvs.Set(k, r.Field(k, v))
+	}
+	return out
+}
+
+type redactedMessage struct {
+	Composer
+	redactor *Redactor
+
+	mu       sync.Mutex
+	resolved bool
+	str      string
+	raw      any
+}
+
+// Redact wraps a message so that its String and Raw methods return
+// redacted output. The fields of KV and Fields messages are redacted
+// by their names and values (see Redactor,) and the output of other
+// messages is redacted by value: the string form is redacted with
+// Redactor.Text and the raw form with Redactor.Value. Group messages
+// redact each of their messages.
+//
+// The annotations of messages, including those added by loggers
+// (e.g. grip.With), are redacted by name, as are the fields of
+// structs in the raw form of messages. Redact does not modify the
+// underlying message, and annotations added to the redacted message
+// are also redacted.
+func Redact(m Composer, r *Redactor) Composer {
+	if m == nil || r == nil {
+		return m
+	}
+	return &redactedMessage{Composer: m, redactor: r}
+}
+
+func (m *redactedMessage) resolve() {
+	if m.resolved {
+		return
+	}
+
+	inner := m.Composer
+	if future, ok := inner.(*composerFutureMessage); ok {
+		future.resolve()
+		inner = future.cached
+	}
+
+	switch msg := inner.(type) {
+	case *KV:
+		out := m.redactor.kv(msg)
+		m.str, m.raw = out.String(), out.Raw()
+	case *GroupComposer:
+		msgs := msg.Messages()
+		for idx := range msgs {
+			msgs[idx] = Redact(msgs[idx], m.redactor)
+		}
+		out := MakeGroupComposer(msgs)
+		m.str, m.raw = out.String(), out.Raw()
+	default:
+		m.str, m.raw = m.redactor.Text(m.annotatedString(inner)), m.redactor.Value(inner.Raw())
+		if _, ok := m.raw.(error); ok {
+			// the documents of messages that are errors (e.g.
+			// error messages with metadata) hold their
+			// annotations.
+			if doc, ok := jsonDocument(m.raw); ok {
+				m.raw = m.redactor.Value(doc)
+			}
+		}
+	}
+	m.resolved = true
+}
+
+// annotatedString returns the string form of the message, masking the
+// annotations that the string form includes (see
+// OptionRenderExtendedStringOutuput) when their names match.
+func (m *redactedMessage) annotatedString(inner Composer) string {
+	out := inner.String()
+	annotated, ok := inner.(interface {
+		annotations() *dt.OrderedMap[string, any]
+	})
+	if !ok {
+		return out
+	}
+	for key, value := range annotated.annotations().Iterator() {
+		if m.redactor.MatchesKey(key) {
+			out = strings.ReplaceAll(out, renderField(key, value), renderField(key, m.redactor.mask()))
+		}
+	}
+	return out
+}
+
+func (m *redactedMessage) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolve()
+	return m.str
+}
+
+func (m *redactedMessage) Raw() any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolve()
+	return m.raw
+}
+
+func (m *redactedMessage) Annotate(key string, value any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Composer.Annotate(key, value)
+	m.resolved = false
+}
+
+func (m *redactedMessage) SetOption(opts ...Option) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Composer.SetOption(opts...)
+	m.resolved = false
+}
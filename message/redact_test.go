@@ -0,0 +1,212 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/dt"
+	"github.com/tychoish/grip/level"
+)
+
+type secretValue string
+
+func (s secretValue) Redact() any { return "secret:" + strings.Repeat("*", len(s)) }
+
+func TestRedactor(t *testing.T) {
+	r := DefaultRedactor()
+
+	for _, key := range []string{"password", "db_password", "Authorization", "API_KEY", "refresh_token"} {
+		if !r.MatchesKey(key) {
+			t.Error("should match", key)
+		}
+	}
+	for _, key := range []string{"user", "msg", "count"} {
+		if r.MatchesKey(key) {
+			t.Error("should not match", key)
+		}
+	}
+
+	for in, expected := range map[string]string{
+		"card 4111 1111 1111 1111 declined": "card [REDACTED] declined",
+		"card 4111-1111-1111-1111":          "card [REDACTED]",
+		"amex 378282246310005":              "amex [REDACTED]",
+		"Authorization: Bearer abc.def-ghi": "Authorization: [REDACTED]",
+		"sent to someone@example.com":       "sent to [REDACTED]",
+		"request 1712345678901 took 12ms":   "request 1712345678901 took 12ms",
+	} {
+		if out := r.Text(in); out != expected {
+			t.Errorf("%q: got %q, expected %q", in, out, expected)
+		}
+	}
+
+	t.Run("Values", func(t *testing.T) {
+		in := map[string]any{
+			"password": 1234,
+			"nested":   Fields{"token": "abc", "note": "mail a@b.io"},
+			"list":     []any{"x@y.org", secretValue("hunter2")},
+			"headers":  map[string]string{"Cookie": "a=b", "accept": "text/plain"},
+		}
+		out := r.Value(in).(map[string]any)
+		if out["password"] != DefaultRedactionMask {
+			t.Error(out["password"])
+		}
+		nested := out["nested"].(Fields)
+		if nested["token"] != DefaultRedactionMask || nested["note"] != "mail [REDACTED]" {
+			t.Error(nested)
+		}
+		list := out["list"].([]any)
+		if list[0] != DefaultRedactionMask || list[1] != "secret:*******" {
+			t.Error(list)
+		}
+		headers := out["headers"].(map[string]string)
+		if headers["Cookie"] != DefaultRedactionMask || headers["accept"] != "text/plain" {
+			t.Error(headers)
+		}
+		if in["password"] != 1234 || in["nested"].(Fields)["token"] != "abc" {
+			t.Error("input should not be modified", in)
+		}
+	})
+	t.Run("Custom", func(t *testing.T) {
+		r := &Redactor{
+			KeyPatterns: []*regexp.Regexp{regexp.MustCompile(`^ssn$`)},
+			Values:      []*regexp.Regexp{regexp.MustCompile(`\d{3}-\d{2}-\d{4}`)},
+			Mask:        "###",
+		}
+		if r.Field("ssn", 123) != "###" || r.Field("note", "id 123-45-6789") != "id ###" {
+			t.Error("custom redactor")
+		}
+		if r.Field("password", "plain") != "plain" {
+			t.Error("custom redactors should not use the default keys")
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	r := DefaultRedactor()
+
+	t.Run("KV", func(t *testing.T) {
+		kv := NewKV().KV("user", "jo").KV("password", "hunter2").KV("email", "jo@example.com")
+		kv.SetPriority(level.Info)
+		m := Redact(kv, r)
+
+		if m.String() != "user='jo' password='[REDACTED]' email='[REDACTED]'" {
+			t.Error(m.String())
+		}
+		raw := m.Raw().(*dt.OrderedMap[string, any])
+		if raw.Get("password") != DefaultRedactionMask || raw.Get("user") != "jo" {
+			t.Error("raw output should be redacted")
+		}
+		if m.Priority() != level.Info || !m.Loggable() || !m.Structured() {
+			t.Error("redaction should not change the message's properties")
+		}
+		if !strings.Contains(kv.String(), "hunter2") {
+			t.Error("the underlying message should not be modified")
+		}
+
+		m.Annotate("api_key", "xyz")
+		if !strings.Contains(m.String(), "api_key='[REDACTED]'") {
+			t.Error("annotations should be redacted", m.String())
+		}
+	})
+	t.Run("Fields", func(t *testing.T) {
+		m := Redact(MakeFields(Fields{"token": "abc", "msg": "hi"}), r)
+		m.SetOption(OptionIncludeMetadata)
+		out, err := json.Marshal(m.Raw())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(out), "abc") || strings.Contains(m.String(), "abc") {
+			t.Error("fields should be redacted", string(out), m.String())
+		}
+		if !strings.Contains(string(out), `"meta"`) {
+			t.Error("options should apply to the redacted message", string(out))
+		}
+	})
+	t.Run("Unstructured", func(t *testing.T) {
+		m := Redact(MakeString("login failed for jo@example.com"), r)
+		if m.String() != "login failed for [REDACTED]" {
+			t.Error(m.String())
+		}
+
+		m = Redact(MakeError(errors.New("bad card 4111111111111111")), r)
+		if strings.Contains(m.String(), "4111") {
+			t.Error(m.String())
+		}
+	})
+	t.Run("Annotations", func(t *testing.T) {
+		str := MakeString("hello")
+		str.Annotate("token", "abc123")
+		str.Annotate("user", "jo")
+		str.SetOption(OptionRenderExtendedStringOutuput)
+
+		failure := MakeError(errors.New("failed"))
+		failure.Annotate("password", "hunter2")
+
+		withMeta := MakeError(errors.New("failed"))
+		withMeta.Annotate("password", "hunter2")
+		withMeta.SetOption(OptionIncludeMetadata)
+
+		for name, in := range map[string]Composer{"String": str, "Error": failure, "Metadata": withMeta} {
+			m := Redact(in, r)
+			out, err := json.Marshal(m.Raw())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(out), "abc123") || strings.Contains(string(out), "hunter2") {
+				t.Error(name, "annotations should be redacted", string(out))
+			}
+			if strings.Contains(m.String(), "abc123") {
+				t.Error(name, "annotations should be redacted", m.String())
+			}
+			if !strings.Contains(string(out), DefaultRedactionMask) {
+				t.Error(name, string(out))
+			}
+		}
+		if !strings.Contains(Redact(str, r).String(), "user='jo'") {
+			t.Error(Redact(str, r).String())
+		}
+	})
+	t.Run("Structs", func(t *testing.T) {
+		type credentials struct {
+			User     string `json:"user"`
+			Password string `json:"password"`
+		}
+		out := r.Value(map[string]any{"creds": credentials{User: "jo", Password: "hunter2"}, "at": time.Unix(0, 0)})
+		doc := out.(map[string]any)
+		if doc["creds"].(map[string]any)["password"] != DefaultRedactionMask {
+			t.Error(doc)
+		}
+		if _, ok := doc["at"].(time.Time); !ok {
+			t.Error("times should not be converted", doc)
+		}
+		if err := errors.New("a@b.io"); r.Value(err) != err {
+			t.Error("errors should not be converted")
+		}
+
+		m := Redact(MakeStack(1, "a@b.io"), r)
+		trace, ok := m.Raw().(StackTrace)
+		if !ok || len(trace.Frames) == 0 || strings.Contains(fmt.Sprint(trace.Context), "a@b.io") {
+			t.Error(m.Raw())
+		}
+	})
+	t.Run("Group", func(t *testing.T) {
+		m := Redact(MakeGroupComposer([]Composer{
+			NewKV().KV("secret", "s3"),
+			MakeString("a@b.io"),
+		}), r)
+		if strings.Contains(m.String(), "s3") || strings.Contains(m.String(), "a@b.io") {
+			t.Error(m.String())
+		}
+	})
+	t.Run("Nil", func(t *testing.T) {
+		m := MakeString("a@b.io")
+		if Redact(m, nil) != m || Redact(nil, r) != nil {
+			t.Error("nil redactors and messages should pass through")
+		}
+	})
+}
@@ -20,6 +20,9 @@ func (m *strf) Loggable() bool {
 func (m *strf) String() string { return m.rendered.Resolve().Message }
 func (m *strf) Raw() any       { return m.rendered.Resolve().Payload.Resolve() }
 
+// Template returns the format string of the message.
+func (m *strf) Template() string { return m.template }
+
 // MakeFormat returns a message.Composer roughly equivalent to an
 // fmt.Sprintf().
 func MakeFormat(base string, args ...any) Composer {
@@ -0,0 +1,213 @@
+package message
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/fun/dt"
+)
+
+// Struct Conversion
+//
+// Convert renders arbitrary structs using fmt ("%+v"). The struct
+// converter instead produces KV messages from the exported fields of
+// structs, in declaration order, which structured senders and
+// formatters can render as fields. Fields are configured using the
+// `grip` and `json` struct tags:
+//
+//	type Request struct {
+//		ID     string `json:"id"`
+//		User   string `grip:"user,omitempty"`
+//		Token  string `grip:"redact"`
+//		Body   []byte `grip:"-"`
+//	}
+//
+// Names come from the grip tag, then the json tag, and then the name
+// of the field. The "omitempty" option (in either tag) omits fields
+// with zero values, "-" skips the field, and the grip "redact" option
+// replaces the value with DefaultRedactionMask. A tag with only an
+// option (e.g. `grip:"redact"`) keeps the default name. The fields
+// of embedded structs are flattened into the message, as with
+// encoding/json.
+//
+// The converter caches the plan for each type, so that reflection
+// over the struct tags only happens once per type.
+
+// StructConverter returns a Converter that converts structs using
+// ConvertStruct, and all other values using Convert. Use it with
+// grip.MakeLogger:
+//
+//	logger := grip.MakeLogger(sender, message.StructConverter())
+func StructConverter() Converter { return ConverterFunc(ConvertStruct) }
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	redact    bool
+}
+
+var structPlans = &adt.SyncMap[reflect.Type, []structField]{}
+
+// ConvertStruct converts a struct, or a pointer to a struct, into a
+// KV message with one pair for each of the struct's fields. The
+// second value is false for values that are not structs, and for
+// the types that Convert handles specially (e.g. Composers, errors,
+// fmt.Stringers, and Marshalers.) Nil pointers produce a message
+// that is not loggable.
+//
+// The fields are read when the message is created, so the message is
+// not affected by later changes to the struct (e.g. while an
+// asynchronous sender holds it.) Fields that are references (maps,
+// slices, and pointers) still share the referenced values.
+func ConvertStruct(in any) (Composer, bool) {
+	switch in.(type) {
+	case nil, Composer, error, Marshaler, fmt.Stringer, dt.List[string], *dt.List[string],
+		interface{ Iterator() iter.Seq[string] },
+		interface{ Iterator() iter.Seq[any] },
+		interface {
+			Iterator() iter.Seq2[string, string]
+		},
+		interface{ Iterator() iter.Seq2[string, any] }:
+		return nil, false
+	}
+
+	val := reflect.ValueOf(in)
+	if val.Kind() == reflect.Pointer {
+		if val.Type().Elem().Kind() != reflect.Struct {
+			return nil, false
+		}
+		if val.IsNil() {
+			return Noop(), true
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	if zero, ok := in.(interface{ IsZero() bool }); ok && zero.IsZero() {
+		return Noop(), true
+	}
+
+	kv := NewKV()
+	for _, field := range structPlan(val.Type()) {
+		fv, err := val.FieldByIndexErr(field.index)
+		switch {
+		case err != nil || !fv.CanInterface():
+			continue
+		case field.omitEmpty && fv.IsZero():
+			continue
+		case field.redact:
+			kv.KV(field.name, DefaultRedactionMask)
+		default:
+			kv.KV(field.name, fv.Interface())
+		}
+	}
+	return kv, true
+}
+
+func structPlan(t reflect.Type) []structField {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan
+	}
+
+	// when several fields have the same name, the least nested
+	// field wins, and otherwise the first field wins.
+	fields := buildStructPlan(t, nil, []reflect.Type{t})
+	depth := map[string]int{}
+	for _, field := range fields {
+		if d, ok := depth[field.name]; !ok || len(field.index) < d {
+			depth[field.name] = len(field.index)
+		}
+	}
+
+	plan := make([]structField, 0, len(depth))
+	for _, field := range fields {
+		if d, ok := depth[field.name]; ok && d == len(field.index) {
+			plan = append(plan, field)
+			delete(depth, field.name)
+		}
+	}
+
+	structPlans.Store(t, plan)
+	return plan
+}
+
+func buildStructPlan(t reflect.Type, parent []int, chain []reflect.Type) []structField {
+	var out []structField
+	for idx := range t.NumField() {
+		field := t.Field(idx)
+		index := append(append([]int{}, parent...), idx)
+
+		name, omitEmpty, redact, skip := parseStructTags(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// recursive embedding is skipped
+				if !slices.Contains(chain, ft) {
+					out = append(out, buildStructPlan(ft, index, append(chain, ft))...)
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		out = append(out, structField{name: name, index: index, omitEmpty: omitEmpty, redact: redact})
+	}
+	return out
+}
+
+func parseStructTags(field reflect.StructField) (name string, omitEmpty, redact, skip bool) {
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		if tag == "-" {
+			return "", false, false, true
+		}
+		jname, opts, _ := strings.Cut(tag, ",")
+		name = jname
+		omitEmpty = hasTagOption(opts, "omitempty")
+	}
+
+	if tag, ok := field.Tag.Lookup("grip"); ok {
+		if tag == "-" {
+			return "", false, false, true
+		}
+		gname, opts, hasOpts := strings.Cut(tag, ",")
+		if !hasOpts && (gname == "redact" || gname == "omitempty") {
+			gname, opts = "", gname
+		}
+		if gname != "" {
+			name = gname
+		}
+		omitEmpty = omitEmpty || hasTagOption(opts, "omitempty")
+		redact = hasTagOption(opts, "redact")
+	}
+
+	return name, omitEmpty, redact, false
+}
+
+func hasTagOption(opts, option string) bool {
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package message
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/dt"
+	"github.com/tychoish/grip/level"
+)
+
+type structBase struct {
+	ID      string `json:"id"`
+	Version int
+}
+
+type Audit struct {
+	Actor string
+}
+
+type structRequest struct {
+	structBase
+	*Audit
+	ID       string        `grip:"request_id"`
+	User     string        `json:"user,omitempty"`
+	Email    string        `grip:",omitempty"`
+	Token    string        `json:"token" grip:"redact"`
+	Password string        `grip:"pw,redact"`
+	Body     []byte        `grip:"-"`
+	Internal string        `json:"-"`
+	Elapsed  time.Duration `json:"elapsed"`
+	hidden   string
+}
+
+type recursive struct {
+	*recursive
+	Name string
+}
+
+func TestConvertStruct(t *testing.T) {
+	req := structRequest{
+		structBase: structBase{ID: "base", Version: 2},
+		ID:         "req",
+		Token:      "abc",
+		Password:   "hunter2",
+		Body:       []byte("body"),
+		Internal:   "internal",
+		Elapsed:    time.Second,
+		hidden:     "hidden",
+	}
+
+	for name, in := range map[string]any{"Value": req, "Pointer": &req} {
+		t.Run(name, func(t *testing.T) {
+			m, ok := ConvertStruct(in)
+			if !ok {
+				t.Fatal("should convert struct")
+			}
+			m.SetPriority(level.Info)
+			if !m.Loggable() {
+				t.Fatal("should be loggable")
+			}
+			expected := "id='base' Version='2' request_id='req' token='[REDACTED]' pw='[REDACTED]' elapsed='1s'"
+			if m.String() != expected {
+				t.Errorf("got %q\nexpected %q", m.String(), expected)
+			}
+			raw := m.Raw().(*dt.OrderedMap[string, any])
+			if raw.Get("Version") != 2 || raw.Get("elapsed") != time.Second {
+				t.Error("raw values should not be rendered", raw)
+			}
+		})
+	}
+	t.Run("Embedded", func(t *testing.T) {
+		m, _ := ConvertStruct(structRequest{Audit: &Audit{Actor: "jo"}, User: "u", Email: "e"})
+		for _, key := range []string{"Actor", "user", "Email"} {
+			if !m.Raw().(*dt.OrderedMap[string, any]).Check(key) {
+				t.Error("missing", key, m.String())
+			}
+		}
+
+		m, ok := ConvertStruct(recursive{Name: "r"})
+		if !ok || m.String() != "Name='r'" {
+			t.Error(m)
+		}
+	})
+	t.Run("Passthrough", func(t *testing.T) {
+		for _, in := range []any{nil, "str", 42, errors.New("err"), time.Now(), &dt.List[string]{}, dt.List[string]{}, Fields{"a": 1}, []int{1}, new(int)} {
+			if _, ok := ConvertStruct(in); ok {
+				t.Errorf("%T should not be converted", in)
+			}
+		}
+
+		var nilReq *structRequest
+		m, ok := ConvertStruct(nilReq)
+		if !ok || m.Loggable() {
+			t.Error("nil pointers should not be loggable")
+		}
+	})
+	t.Run("Converter", func(t *testing.T) {
+		conv := StructConverter()
+		if out := conv.Convert(Audit{Actor: "jo"}).String(); out != "Actor='jo'" {
+			t.Error(out)
+		}
+		if out := conv.Convert("hello").String(); out != "hello" {
+			t.Error(out)
+		}
+	})
+	t.Run("Cache", func(t *testing.T) {
+		_, _ = ConvertStruct(Audit{})
+		plan, ok := structPlans.Load(reflect.TypeFor[Audit]())
+		if !ok || len(plan) != 1 || plan[0].name != "Actor" {
+			t.Error("plan should be cached", plan)
+		}
+	})
+	t.Run("Snapshot", func(t *testing.T) {
+		// run with -race: the message is rendered concurrently
+		// with changes to the struct, as with asynchronous
+		// senders.
+		in := &Audit{Actor: "jo"}
+		m, ok := ConvertStruct(in)
+		if !ok {
+			t.Fatal("should convert")
+		}
+
+		done := make(chan string)
+		go func() { done <- m.String() }()
+		in.Actor = "changed"
+		if out := <-done; out != "Actor='jo'" {
+			t.Error(out)
+		}
+		if out := m.String(); out != "Actor='jo'" {
+			t.Error(out)
+		}
+	})
+}
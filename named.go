@@ -0,0 +1,295 @@
+package grip
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/grip/level"
+)
+
+// Named Loggers
+//
+// Named loggers provide per-component level control. Every named
+// logger has a dotted name (e.g. "db.pool") and shares the sender and
+// converter of its parent (ultimately the standard logger,) so that
+// changes to the global sender (via SetSender) are seen by all named
+// loggers. Each named logger carries its own threshold, which is
+// resolved from the level rules (see SetLevelRule) that match its
+// name.
+//
+// A rule replaces the sender's threshold for the loggers it matches,
+// so rules may lower a component's level as well as raise it. As with
+// send.MakeMasked, when a rule is below the priority of the standard
+// logger's sender, the sender's priority is lowered to the lowest
+// rule, and its previous priority becomes the threshold of the
+// standard logger. Named loggers that no rule matches follow the
+// threshold of the standard logger, and the rules are applied again
+// when the standard logger's sender or threshold changes.
+// Logger.Threshold reports the effective threshold.
+
+var named = &namedRegistry{
+	loggers: map[string]Logger{},
+	rules:   map[string]level.Priority{},
+}
+
+type namedRegistry struct {
+	mu      sync.Mutex
+	loggers map[string]Logger
+	rules   map[string]level.Priority
+}
+
+// Named returns the logger registered with the given dotted name,
+// creating it (and any missing parents) if needed. The empty name
+// resolves to the standard logger.
+//
+// Named loggers share the standard logger's sender rather than a
+// copy of it: calling SetSender (or SwapSender) on a named logger
+// replaces the sender of the standard logger and of every other
+// named logger. Use Clone to derive a logger with its own sender.
+func Named(name string) Logger { return named.get(name) }
+
+// NamedLoggers returns an iterator over all registered named loggers
+// in name order.
+func NamedLoggers() iter.Seq2[string, Logger] { return named.iterator() }
+
+// Named creates a child of the logger with the name appended (dotted)
+// to the logger's name. The child shares the sender and converter of
+// its parent, and begins with the parent's threshold. Unlike the
+// package-level Named function, the child is not registered and is
+// not affected by level rules.
+func (g Logger) Named(name string) Logger {
+	if g.name != "" {
+		name = g.name + "." + name
+	}
+
+	out := g
+	out.lvl = adt.NewAtomic(g.lvl.Get())
+	out.name = name
+	return out
+}
+
+// SetLevelRule sets the threshold for all named loggers matching the
+// pattern. Patterns are either exact names ("db.pool"), a prefix
+// followed by ".*" that matches the prefix and all of its descendants
+// ("db.*"), or "*" which matches every named logger. When several
+// rules match a name, exact matches take precedence, followed by the
+// longest matching prefix.
+//
+// Use level.Invalid to remove a rule. SetLevelRule returns an error,
+// and does not change the rules, if the pattern is not one of the
+// supported forms (e.g. "db*" or "db..pool".)
+func SetLevelRule(pattern string, p level.Priority) error {
+	if err := validatePattern(pattern); err != nil {
+		return err
+	}
+	named.setRule(pattern, p)
+	return nil
+}
+
+// SetLevelRules parses a comma separated list of "pattern=level"
+// rules (e.g. "db.*=debug,http=warning") and applies them with
+// SetLevelRule. Levels are parsed with level.FromString. No rules are
+// applied if any of the rules are invalid.
+func SetLevelRules(spec string) error {
+	rules, err := ParseLevelRules(spec)
+	if err != nil {
+		return err
+	}
+	for pattern, p := range rules {
+		named.setRule(pattern, p)
+	}
+	return nil
+}
+
+// ParseLevelRules parses a comma separated list of "pattern=level"
+// rules, as used by SetLevelRules.
+func ParseLevelRules(spec string) (map[string]level.Priority, error) {
+	out := map[string]level.Priority{}
+	for rule := range strings.SplitSeq(spec, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+
+		pattern, value, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("level rule %q is not of the form pattern=level", rule)
+		}
+
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("level rule %q does not specify a pattern", rule)
+		}
+		if err := validatePattern(pattern); err != nil {
+			return nil, fmt.Errorf("level rule %q: %w", rule, err)
+		}
+
+		p := level.FromString(value)
+		if p == level.Invalid {
+			return nil, fmt.Errorf("level rule %q has invalid level %q", rule, value)
+		}
+		out[pattern] = p
+	}
+	return out, nil
+}
+
+// validatePattern returns an error unless the pattern is "*", a
+// dotted name, or a dotted name followed by ".*".
+func validatePattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+
+	name, _ := strings.CutSuffix(pattern, ".*")
+	if strings.Contains(name, "*") {
+		return fmt.Errorf("level rule pattern %q is not supported: wildcards must be \"*\" or a \".*\" suffix", pattern)
+	}
+	for segment := range strings.SplitSeq(name, ".") {
+		if segment == "" {
+			return fmt.Errorf("level rule pattern %q has an empty name segment", pattern)
+		}
+	}
+	return nil
+}
+
+// LevelRules returns a copy of the current level rules.
+func LevelRules() map[string]level.Priority { return named.copyRules() }
+
+func (r *namedRegistry) get(name string) Logger {
+	if name == "" {
+		return std
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resolve(name)
+}
+
+// resolve must be called with the lock held.
+func (r *namedRegistry) resolve(name string) Logger {
+	if l, ok := r.loggers[name]; ok {
+		return l
+	}
+
+	parent := std
+	child := name
+	if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+		parent = r.resolve(name[:idx])
+		child = name[idx+1:]
+	}
+
+	l := parent.Named(child)
+	l.SetThreshold(r.threshold(name))
+	r.loggers[name] = l
+	return l
+}
+
+func (r *namedRegistry) setRule(pattern string, p level.Priority) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p == level.Invalid {
+		delete(r.rules, pattern)
+	} else {
+		r.rules[pattern] = p
+	}
+
+	r.apply()
+}
+
+// reapply applies the rules again after the standard logger's sender
+// or threshold changes.
+func (r *namedRegistry) reapply() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply()
+}
+
+// apply lowers the priority of the standard logger's sender to the
+// lowest rule, if needed, and then sets the threshold of every named
+// logger. apply must be called with the lock held.
+func (r *namedRegistry) apply() {
+	floor := level.Invalid
+	for _, p := range r.rules {
+		if floor == level.Invalid || p < floor {
+			floor = p
+		}
+	}
+
+	if s := std.Sender(); s != nil && floor != level.Invalid && floor < s.Priority() {
+		std.lvl.Set(max(std.lvl.Get(), s.Priority()))
+		s.SetPriority(floor)
+	}
+
+	for name, l := range r.loggers {
+		l.SetThreshold(r.threshold(name))
+	}
+}
+
+// threshold returns the threshold for the named logger: the level of
+// the most specific matching rule, or the standard logger's threshold
+// if no rule matches. threshold must be called with the lock held.
+func (r *namedRegistry) threshold(name string) level.Priority {
+	if p := r.match(name); p != level.Invalid {
+		return p
+	}
+	return std.lvl.Get()
+}
+
+// match returns the threshold from the most specific rule that
+// matches the name, or level.Invalid if no rule matches. match must
+// be called with the lock held.
+func (r *namedRegistry) match(name string) level.Priority {
+	if p, ok := r.rules[name]; ok {
+		return p
+	}
+
+	out := level.Invalid
+	longest := -1
+	for pattern, p := range r.rules {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prefix == "":
+		case strings.HasSuffix(prefix, "."):
+			if name+"." != prefix && !strings.HasPrefix(name, prefix) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if len(prefix) > longest {
+			longest = len(prefix)
+			out = p
+		}
+	}
+	return out
+}
+
+func (r *namedRegistry) copyRules() map[string]level.Priority {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.rules)
+}
+
+func (r *namedRegistry) iterator() iter.Seq2[string, Logger] {
+	r.mu.Lock()
+	loggers := maps.Clone(r.loggers)
+	r.mu.Unlock()
+
+	return func(yield func(string, Logger) bool) {
+		for _, name := range slices.Sorted(maps.Keys(loggers)) {
+			if !yield(name, loggers[name]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package grip
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+func TestNamedLoggers(t *testing.T) {
+	// resetRules removes the rules after the test, and restores the
+	// standard logger's sender and threshold, which rules may lower.
+	resetRules := func(t *testing.T) {
+		t.Helper()
+		sender, priority, threshold := Sender(), Sender().Priority(), std.lvl.Get()
+		t.Cleanup(func() {
+			for pattern := range LevelRules() {
+				SetLevelRule(pattern, level.Invalid)
+			}
+			std.SetThreshold(threshold)
+			sender.SetPriority(priority)
+			SetSender(sender)
+		})
+	}
+
+	t.Run("RootIsStandard", func(t *testing.T) {
+		check.True(t, Named("") == std)
+	})
+	t.Run("Registered", func(t *testing.T) {
+		one := Named("test.registered.one")
+		check.Equal(t, one.Name(), "test.registered.one")
+		check.True(t, one == Named("test.registered.one"))
+
+		var seen []string
+		for name := range NamedLoggers() {
+			seen = append(seen, name)
+		}
+		check.True(t, len(seen) >= 3)
+		for idx := 1; idx < len(seen); idx++ {
+			check.True(t, seen[idx-1] < seen[idx])
+		}
+	})
+	t.Run("SharesSender", func(t *testing.T) {
+		l := Named("test.shares")
+		check.True(t, l.Sender() == Sender())
+		check.Equal(t, l.Threshold(), Sender().Priority())
+
+		// setting the sender of a named logger sets the global sender
+		resetRules(t)
+		sender := send.MakeInternal()
+		l.SetSender(sender)
+		check.True(t, Sender() == sender)
+		check.True(t, Named("test.other").Sender() == sender)
+	})
+	t.Run("Rules", func(t *testing.T) {
+		resetRules(t)
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+		SetSender(sender)
+
+		pool := Named("test.rules.pool")
+		other := Named("test.other")
+
+		// rules below the sender's priority lower it, and the
+		// remaining loggers keep the sender's previous priority
+		check.NotError(t, SetLevelRule("test.rules.*", level.Debug))
+		check.Equal(t, sender.Priority(), level.Debug)
+		check.Equal(t, pool.Threshold(), level.Debug)
+		check.Equal(t, Named("test.rules").Threshold(), level.Debug)
+		check.Equal(t, other.Threshold(), level.Info)
+		check.Equal(t, std.Threshold(), level.Info)
+		check.True(t, pool.Enabled(level.Debug))
+		check.True(t, !other.Enabled(level.Debug))
+
+		pool.Debug("kept")
+		other.Debug("dropped")
+		Debug("dropped")
+		check.Equal(t, sender.Len(), 1)
+
+		SetLevelRule("*", level.Error)
+		check.Equal(t, pool.Threshold(), level.Debug)
+		check.Equal(t, other.Threshold(), level.Error)
+
+		SetLevelRule("test.rules.pool", level.Alert)
+		check.Equal(t, pool.Threshold(), level.Alert)
+
+		// loggers created after the rules are set get the threshold
+		check.Equal(t, Named("test.rules.pool.conn").Threshold(), level.Debug)
+
+		SetLevelRule("test.rules.pool", level.Invalid)
+		check.Equal(t, pool.Threshold(), level.Debug)
+
+		// replacing the standard logger's sender applies the rules
+		// to the new sender
+		next := send.MakeInternal()
+		next.SetPriority(level.Warning)
+		SetSender(next)
+		check.Equal(t, next.Priority(), level.Debug)
+		check.Equal(t, std.Threshold(), level.Warning)
+		check.Equal(t, pool.Threshold(), level.Debug)
+		check.Equal(t, other.Threshold(), level.Error)
+	})
+	t.Run("EffectiveThreshold", func(t *testing.T) {
+		resetRules(t)
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Info)
+
+		logger := NewLogger(sender).Named("effective")
+		logger.SetThreshold(level.Debug)
+		check.Equal(t, logger.Threshold(), level.Info)
+		check.True(t, !logger.Enabled(level.Debug))
+		logger.Debug("dropped")
+		check.Equal(t, sender.Len(), 0)
+
+		logger.SetThreshold(level.Error)
+		check.Equal(t, logger.Threshold(), level.Error)
+		check.True(t, !logger.Enabled(level.Warning))
+		check.True(t, logger.Enabled(level.Error))
+	})
+	t.Run("InvalidPatterns", func(t *testing.T) {
+		resetRules(t)
+		for _, pattern := range []string{"db*", "*.pool", "db.*.pool", "db..pool", ".db", "db.", ".*", ""} {
+			check.Error(t, SetLevelRule(pattern, level.Debug))
+			check.Error(t, SetLevelRules(pattern+"=debug"))
+		}
+		check.Equal(t, len(LevelRules()), 0)
+
+		for _, pattern := range []string{"*", "db", "db.pool", "db.*"} {
+			check.NotError(t, SetLevelRule(pattern, level.Debug))
+		}
+		check.Equal(t, len(LevelRules()), 4)
+	})
+	t.Run("ParseRules", func(t *testing.T) {
+		resetRules(t)
+		rules, err := ParseLevelRules(" db.*=debug, http=Warning,")
+		check.NotError(t, err)
+		check.Equal(t, len(rules), 2)
+		check.Equal(t, rules["db.*"], level.Debug)
+		check.Equal(t, rules["http"], level.Warning)
+
+		for _, spec := range []string{"db", "=debug", "db=bogus"} {
+			_, err := ParseLevelRules(spec)
+			check.Error(t, err)
+			check.Error(t, SetLevelRules(spec))
+		}
+		check.Equal(t, len(LevelRules()), 0)
+
+		check.NotError(t, SetLevelRules("test.parse.*=trace"))
+		check.Equal(t, LevelRules()["test.parse.*"], level.Trace)
+	})
+	t.Run("ThresholdFilters", func(t *testing.T) {
+		sender := send.MakeInternal()
+		sender.SetPriority(level.Trace)
+
+		logger := NewLogger(sender).Named("filtered")
+		check.Equal(t, logger.Name(), "filtered")
+		check.Equal(t, logger.Named("child").Name(), "filtered.child")
+
+		logger.SetThreshold(level.Warning)
+		logger.Info("dropped")
+		logger.Build().Ln("dropped").Level(level.Info).Send()
+		check.Equal(t, sender.Len(), 0)
+
+		logger.Error("kept")
+		logger.Send(message.MakeString("unset"))
+		check.Equal(t, sender.Len(), 1)
+
+		clone := logger.Clone()
+		check.Equal(t, clone.Name(), "filtered")
+		check.Equal(t, clone.Threshold(), level.Warning)
+		clone.SetThreshold(level.Invalid)
+		check.Equal(t, logger.Threshold(), level.Warning)
+		check.Equal(t, clone.Threshold(), level.Trace)
+	})
+	t.Run("Context", func(t *testing.T) {
+		ctx := WithNamedLogger(context.Background(), "test.context")
+		check.True(t, HasContextLogger(ctx, "test.context"))
+		check.True(t, ContextLogger(ctx, "test.context") == Named("test.context"))
+		check.True(t, WithNamedLogger(ctx, "test.context") == ctx)
+	})
+}
@@ -0,0 +1,30 @@
+package grip
+
+import (
+	"context"
+
+	"github.com/tychoish/grip/send"
+)
+
+// Sampled returns a copy of the logger that samples and rate limits
+// the messages it sends, as configured by the options (see
+// send.SamplingOptions.) Only messages that would be logged are
+// counted. Until the context is canceled, the returned logger sends a
+// summary of the suppressed messages through the original logger at
+// every interval; a final summary is sent when the context is
+// canceled.
+//
+// Unlike wrapping the sender with send.MakeSampling, the sampled
+// logger continues to share the sender of the original logger, so
+// that sampling can apply to a single component. Loggers derived from
+// a sampled logger (e.g. with With) share its sampler.
+func (g Logger) Sampled(ctx context.Context, opts send.SamplingOptions) Logger {
+	out := g
+	out.sampler = send.NewSampler(opts)
+	go out.sampler.Run(ctx, g.Send)
+	return out
+}
+
+// Sampled returns a sampled copy of the standard logger; see
+// Logger.Sampled.
+func Sampled(ctx context.Context, opts send.SamplingOptions) Logger { return std.Sampled(ctx, opts) }
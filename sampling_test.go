@@ -0,0 +1,45 @@
+package grip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/send"
+)
+
+func TestSampled(t *testing.T) {
+	sink := send.MakeInternal()
+	sink.SetPriority(level.Info)
+	logger := NewLogger(sink)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	sampled := logger.Sampled(ctx, send.SamplingOptions{First: 2, Interval: time.Hour}).With("component", "db")
+
+	for range 10 {
+		sampled.Info("retrying connection")
+		sampled.Debug("below threshold")
+	}
+	check.Equal(t, sink.Len(), 2)
+	check.Equal(t, sink.GetMessage().Rendered, "retrying connection")
+
+	// the original logger is not sampled
+	for range 5 {
+		logger.Info("unsampled")
+	}
+	check.Equal(t, sink.Len(), 1+5)
+
+	cancel()
+	for start := time.Now(); sink.Len() < 7 && time.Since(start) < time.Second; {
+		time.Sleep(time.Millisecond)
+	}
+	check.Equal(t, sink.Len(), 7)
+	for range 6 {
+		sink.GetMessage()
+	}
+	summary := sink.GetMessage()
+	check.Equal(t, summary.Priority, level.Info)
+	check.Substring(t, summary.Rendered, "suppressed='8'")
+}
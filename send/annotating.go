@@ -1,6 +1,8 @@
 package send
 
 import (
+	"context"
+
 	"github.com/tychoish/grip/message"
 )
 
@@ -30,13 +32,24 @@ func MakeAnnotating(s Sender, annotations map[string]any) Sender {
 func (s *annotatingSender) Unwrap() Sender { return s.Sender }
 
 func (s *annotatingSender) Send(m message.Composer) {
+	if s.annotate(m) {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *annotatingSender) SendContext(ctx context.Context, m message.Composer) {
+	if s.annotate(m) {
+		SendContext(ctx, s.Sender, m)
+	}
+}
+
+func (s *annotatingSender) annotate(m message.Composer) bool {
 	if !ShouldLog(s, m) {
-		return
+		return false
 	}
 
 	for k, v := range s.annotations {
 		m.Annotate(k, v)
 	}
-
-	s.Sender.Send(m)
+	return true
 }
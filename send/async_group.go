@@ -2,81 +2,112 @@ package send
 
 import (
 	"context"
-	"runtime"
+	"errors"
+	"slices"
 	"sync"
 
 	"github.com/tychoish/fun/erc"
 	"github.com/tychoish/fun/fnx"
-	"github.com/tychoish/fun/pubsub"
 	"github.com/tychoish/grip/level"
 	"github.com/tychoish/grip/message"
 )
 
 type asyncGroupSender struct {
-	broker         *pubsub.Broker[message.Composer]
-	senders        *pubsub.Deque[Sender]
+	mtx            sync.RWMutex
+	workers        []asyncWorker
+	closed         bool
 	wg             fnx.WaitGroup
 	cancel         context.CancelFunc
 	ctx            context.Context
 	baseCtx        context.Context
 	shutdownSignal <-chan struct{}
 	doClose        sync.Once
+	pending        asyncPending
+	bufferSize     int
 	Base
 }
 
+type asyncWorker struct {
+	sender Sender
+	pipe   chan message.Composer
+}
+
+// asyncPending counts the deliveries (one for each message and
+// underlying sender) that have been accepted but not yet completed,
+// and signals waiters when the count reaches zero.
+type asyncPending struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (p *asyncPending) add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n += n
+	if p.n == 0 && p.idle != nil {
+		close(p.idle)
+		p.idle = nil
+	}
+}
+
+// signal returns nil if there are no pending deliveries, and
+// otherwise a channel that is closed when they complete.
+func (p *asyncPending) signal() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.n == 0 {
+		return nil
+	}
+	if p.idle == nil {
+		p.idle = make(chan struct{})
+	}
+	return p.idle
+}
+
 // MakeAsyncGroup produces an implementation of the Sender interface
 // that, like the MultiSender, distributes a single message to a group
 // of underlying sender implementations.
 //
 // This sender does not guarantee ordering of messages. The buffer
 // size controls the size of the buffer between each sender and the
-// individual senders.
+// individual senders; Send blocks while the buffer of any underlying
+// sender is full.
 //
 // The sender takes ownership of the underlying Senders, so closing
 // this sender closes all underlying Senders.
 func MakeAsyncGroup(ctx context.Context, bufferSize int, senders ...Sender) Sender {
 	s := &asyncGroupSender{
-		baseCtx: ctx,
-		// unlimited number of senders, bufferSize is
-		// constrained buy the buffer size in the broker.
-		senders: erc.Must(pubsub.NewDeque[Sender](pubsub.DequeOptions{Unlimited: true})),
-		broker: pubsub.NewBroker[message.Composer](ctx, pubsub.BrokerOptions{
-			BufferSize:       bufferSize,
-			ParallelDispatch: true,
-			WorkerPoolSize:   runtime.NumCPU(),
-		}),
-	}
-	for idx := range senders {
-		erc.Invariant(s.senders.PushBack(senders[idx]), "populate senders")
+		baseCtx:    ctx,
+		bufferSize: max(bufferSize, 0),
 	}
 
 	shutdown := make(chan struct{})
 	s.shutdownSignal = shutdown
 	s.ctx, s.cancel = context.WithCancel(ctx)
 
-	for i := 0; i < len(senders); i++ {
-		s.startSenderWorker(senders[i])
+	// the workers exist before the constructor returns, so every
+	// message sent reaches every sender.
+	for idx := range senders {
+		erc.Invariant(s.startSenderWorker(senders[idx]), "populate senders")
 	}
+
 	wg := &s.wg
 	s.closer.Set(func() (err error) {
 		s.doClose.Do(func() {
 			ec := &erc.Collector{}
 			defer func() { err = ec.Resolve() }()
 			defer s.cancel()
-			ec.Push(s.senders.Close())
 
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				for sender := range s.senders.IteratorFront(ctx) {
-					ec.Push(sender.Close())
-				}
-			}()
+			s.mtx.Lock()
+			s.closed = true
+			s.mtx.Unlock()
 
-			ec.Push(s.senders.Close())
 			close(shutdown)
 			wg.Wait(ctx)
-			s.cancel()
+			for _, w := range s.getWorkers() {
+				ec.Push(w.sender.Close())
+			}
 		})
 
 		// let the defer in the closer set the err
@@ -85,67 +116,121 @@ func MakeAsyncGroup(ctx context.Context, bufferSize int, senders ...Sender) Send
 	return s
 }
 
-func (s *asyncGroupSender) startSenderWorker(newSender Sender) {
+// startSenderWorker adds the sender to the group, and delivers
+// messages from its buffer to the sender until the group is closed
+// or the context is canceled. Messages that are still buffered when
+// the worker stops are discarded, and no longer count as pending.
+func (s *asyncGroupSender) startSenderWorker(sender Sender) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return errors.New("async group sender is closed")
+	}
+
+	pipe := make(chan message.Composer, s.bufferSize)
+	// copy on write, so that senders can iterate over the workers
+	// without holding the lock.
+	s.workers = append(slices.Clip(s.workers), asyncWorker{sender: sender, pipe: pipe})
+
 	s.wg.Add(1)
-	go func(pipe chan message.Composer, sender Sender) {
+	go func() {
 		defer s.wg.Done()
+		defer func() { s.pending.add(-len(pipe)) }()
 		for {
 			select {
 			case <-s.shutdownSignal:
-				s.broker.Unsubscribe(s.baseCtx, pipe)
 				return
 			case <-s.ctx.Done():
 				return
 			case m := <-pipe:
-				if m != nil {
-					sender.Send(m)
-				}
+				sender.Send(m)
+				s.pending.add(-1)
 			}
 		}
-	}(s.broker.Subscribe(s.baseCtx), newSender)
+	}()
+	return nil
+}
+
+func (s *asyncGroupSender) getWorkers() []asyncWorker {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.workers
 }
 
 func (s *asyncGroupSender) SetPriority(p level.Priority) {
 	s.Base.SetPriority(p)
-	fnx.WithContextCall(func(ctx context.Context) {
-		for sender := range s.senders.IteratorFront(ctx) {
-			sender.SetPriority(p)
-		}
-	})
+	for _, w := range s.getWorkers() {
+		w.sender.SetPriority(p)
+	}
 }
 
 func (s *asyncGroupSender) SetErrorHandler(erh ErrorHandler) {
 	s.Base.SetErrorHandler(erh)
-
-	fnx.WithContextCall(func(ctx context.Context) {
-		for sender := range s.senders.IteratorFront(ctx) {
-			sender.SetErrorHandler(erh)
-		}
-	})
+	for _, w := range s.getWorkers() {
+		w.sender.SetErrorHandler(erh)
+	}
 }
 
 func (s *asyncGroupSender) SetFormatter(fmtr MessageFormatter) {
 	s.Base.SetFormatter(fmtr)
-	fnx.WithContextCall(func(ctx context.Context) {
-		for sender := range s.senders.IteratorFront(ctx) {
-			sender.SetFormatter(fmtr)
-		}
-	})
+	for _, w := range s.getWorkers() {
+		w.sender.SetFormatter(fmtr)
+	}
 }
 
-func (s *asyncGroupSender) Send(m message.Composer) {
+// Send queues the message for each of the underlying senders.
+// Messages remain pending until the underlying sender's Send returns;
+// messages that are dropped because the group is closed or its
+// context is canceled stop being pending, so they do not delay Flush.
+func (s *asyncGroupSender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+
+// SendContext is like Send, except that it stops waiting for buffer
+// space when the context ends, and drops the message for the
+// underlying senders that it has not yet reached.
+func (s *asyncGroupSender) SendContext(ctx context.Context, m message.Composer) {
 	if !ShouldLog(s, m) {
 		return
 	}
-	s.broker.Publish(s.ctx, m)
+
+	for _, w := range s.getWorkers() {
+		s.pending.add(1)
+		select {
+		case w.pipe <- m:
+		case <-s.shutdownSignal:
+			s.pending.add(-1)
+		case <-s.ctx.Done():
+			s.pending.add(-1)
+		case <-ctx.Done():
+			s.pending.add(-1)
+		}
+	}
 }
 
+// Flush waits for the messages that have already been sent to
+// reach the underlying senders, and then flushes them.
 func (s *asyncGroupSender) Flush(ctx context.Context) error {
 	catcher := &erc.Collector{}
-	fnx.WithContextCall(func(ctx context.Context) {
-		for sender := range s.senders.IteratorFront(ctx) {
-			catcher.Push(sender.Flush(ctx))
-		}
-	})
+	catcher.Push(s.wait(ctx))
+	for _, w := range s.getWorkers() {
+		catcher.Push(w.sender.Flush(ctx))
+	}
 	return catcher.Resolve()
 }
+
+func (s *asyncGroupSender) wait(ctx context.Context) error {
+	idle := s.pending.signal()
+	if idle == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return nil
+	case <-s.shutdownSignal:
+		return nil
+	case <-idle:
+		return nil
+	}
+}
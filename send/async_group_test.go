@@ -56,3 +56,123 @@ func TestAsyncGroupSender(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestAsyncGroupFlushDelivers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	internal := MakeInternal()
+	internal.SetPriority(level.Info)
+	s := MakeAsyncGroup(ctx, 16, internal)
+	s.SetPriority(level.Info)
+
+	for range 10 {
+		s.Send(NewString(level.Info, "hello"))
+	}
+
+	if err := s.Flush(testt.ContextWithTimeout(t, time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if n := internal.Len(); n != 10 {
+		t.Errorf("flush returned with %d of 10 messages delivered", n)
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAsyncGroupAccounting(t *testing.T) {
+	t.Run("AllSenders", func(t *testing.T) {
+		senders := []*InternalSender{MakeInternal(), MakeInternal(), MakeInternal()}
+		s := MakeAsyncGroup(t.Context(), 2, senders[0], senders[1], senders[2])
+		s.SetPriority(level.Info)
+
+		// messages sent immediately after construction reach
+		// every sender.
+		for range 5 {
+			s.Send(NewString(level.Info, "hello"))
+		}
+		if err := s.Flush(testt.ContextWithTimeout(t, time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		for idx, sender := range senders {
+			if n := sender.Len(); n != 5 {
+				t.Errorf("sender %d received %d of 5 messages", idx, n)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("Dropped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		s := MakeAsyncGroup(ctx, 1, MakeInternal(), MakeInternal())
+		s.SetPriority(level.Info)
+		cancel()
+
+		for range 5 {
+			s.Send(NewString(level.Info, "dropped"))
+		}
+		if n := s.(*asyncGroupSender).pending.signal(); n != nil {
+			select {
+			case <-n:
+			case <-time.After(time.Second):
+				t.Error("dropped messages are still pending")
+			}
+		}
+		if err := s.Flush(testt.ContextWithTimeout(t, time.Second)); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("Closed", func(t *testing.T) {
+		s := MakeAsyncGroup(t.Context(), 1, MakeInternal())
+		s.SetPriority(level.Info)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		for range 5 {
+			s.Send(NewString(level.Info, "dropped"))
+		}
+		if err := s.Flush(testt.ContextWithTimeout(t, time.Second)); err != nil {
+			t.Error(err)
+		}
+		if dur := time.Since(start); dur > 100*time.Millisecond {
+			t.Errorf("send and flush after close took %s", dur)
+		}
+	})
+}
+
+type blockingTestSender struct {
+	Base
+	unblock chan struct{}
+}
+
+func (s *blockingTestSender) Send(m message.Composer) {
+	if ShouldLog(s, m) {
+		<-s.unblock
+	}
+}
+
+func TestAsyncGroupSendContext(t *testing.T) {
+	blocked := &blockingTestSender{unblock: make(chan struct{})}
+	s := MakeAsyncGroup(t.Context(), 0, blocked)
+	s.SetPriority(level.Info)
+	defer close(blocked.unblock)
+
+	// the first message occupies the worker, and without buffer
+	// space the second waits until the deadline.
+	SendContext(t.Context(), s, NewString(level.Info, "one"))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	SendContext(ctx, s, NewString(level.Info, "two"))
+	if dur := time.Since(start); dur > time.Second {
+		t.Errorf("send ignored the deadline, and took %s", dur)
+	}
+	if ctx.Err() == nil {
+		t.Error("send returned before the deadline")
+	}
+}
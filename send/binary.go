@@ -0,0 +1,372 @@
+package send
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// BinaryEncoding selects the encoding of binary log records.
+type BinaryEncoding string
+
+// The binary encodings supported by binary formatters, writers, and
+// readers.
+const (
+	CBOR        BinaryEncoding = "cbor"
+	MessagePack BinaryEncoding = "msgpack"
+	BSON        BinaryEncoding = "bson"
+)
+
+// maxBinaryRecordSize limits the size of the records that binary log
+// readers accept, to avoid allocating buffers for corrupt lengths.
+const maxBinaryRecordSize = 64 << 20
+
+// maxBinaryDepth limits the nesting of values in binary records.
+const maxBinaryDepth = 128
+
+// Binary Logs
+//
+// Binary log streams are sequences of records, each of which is a
+// four byte (big endian) length followed by a document in one of the
+// binary encodings. The document of each record has the following
+// members, in order:
+//
+//	ts       the message's timestamp (see message.Timestamp)
+//	level    the numeric priority of the message
+//	msg      the message
+//	error    the error, for error messages (optional)
+//	stack    the frames of stack messages, as strings (optional)
+//	fields   the fields of the message, as a document (optional)
+//
+// The message, error, stack, and fields are extracted from messages
+// as with MakeEnvelopeFormatter. BSON timestamps have millisecond
+// precision.
+
+// MakeBinaryFormatter returns a MessageFormatter that renders
+// messages as binary log records. The output is not text: use
+// MakeBinaryWriter, which writes the output without modification, to
+// produce binary log streams. Group messages produce one record for
+// each message in the group.
+//
+// The formatter returns an error for unknown encodings, and if the
+// message cannot be encoded (e.g. for BSON documents with keys that
+// contain null bytes.)
+func MakeBinaryFormatter(enc BinaryEncoding) MessageFormatter {
+	var format MessageFormatter
+	format = func(m message.Composer) (string, error) {
+		if group, ok := m.(*message.GroupComposer); ok {
+			var buf strings.Builder
+			for _, msg := range group.Messages() {
+				if !msg.Loggable() {
+					continue
+				}
+				out, err := format(msg)
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(out)
+			}
+			return buf.String(), nil
+		}
+
+		data, err := enc.marshal(binaryRecord(m))
+		if err != nil {
+			return "", err
+		}
+		if len(data) > maxBinaryRecordSize {
+			return "", fmt.Errorf("%s record of %d bytes is too large", enc, len(data))
+		}
+
+		out := make([]byte, 4, len(data)+4)
+		binary.BigEndian.PutUint32(out, uint32(len(data)))
+		return string(append(out, data...)), nil
+	}
+	return format
+}
+
+type binaryWriter struct {
+	mtx sync.Mutex
+	wr  io.Writer
+	Base
+}
+
+// MakeBinaryWriter constructs a Sender that writes binary log records
+// (see MakeBinaryFormatter) to the writer. Unlike MakeWriter, the
+// output of the sender's formatter is written without modification.
+//
+// Writes are fully synchronized with regards to eachother.
+func MakeBinaryWriter(wr io.Writer, enc BinaryEncoding) Sender {
+	s := &binaryWriter{wr: wr}
+	s.SetFormatter(MakeBinaryFormatter(enc))
+	return s
+}
+
+func (s *binaryWriter) Send(m message.Composer) {
+	if ShouldLog(s, m) {
+		if out, err := s.Format(m); s.HandleErrorOK(WrapError(err, m)) {
+			s.mtx.Lock()
+			defer s.mtx.Unlock()
+
+			_, err = io.WriteString(s.wr, out)
+			s.HandleError(WrapError(err, m))
+		}
+	}
+}
+
+// ReadBinaryLog decodes a binary log stream (see MakeBinaryFormatter)
+// into messages, for replay and tests. Each record becomes a KV
+// message, with the priority of the original message, and the
+// message, the fields, and then the error, stack, and time ("ts") of
+// the record as pairs.
+//
+// The sequence ends at the end of the stream. Records that cannot be
+// decoded produce errors, and the sequence continues with the next
+// record (when the caller continues;) errors reading the stream end
+// the sequence.
+func ReadBinaryLog(r io.Reader, enc BinaryEncoding) iter.Seq2[message.Composer, error] {
+	return func(yield func(message.Composer, error) bool) {
+		rd := bufio.NewReader(r)
+		var header [4]byte
+		for {
+			if _, err := io.ReadFull(rd, header[:]); err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, fmt.Errorf("reading %s record: %w", enc, err))
+				}
+				return
+			}
+
+			size := binary.BigEndian.Uint32(header[:])
+			if size > maxBinaryRecordSize {
+				yield(nil, fmt.Errorf("%s record of %d bytes is too large", enc, size))
+				return
+			}
+
+			data := make([]byte, size)
+			if _, err := io.ReadFull(rd, data); err != nil {
+				yield(nil, fmt.Errorf("reading %s record: %w", enc, err))
+				return
+			}
+
+			m, err := decodeBinaryRecord(enc, data)
+			if !yield(m, err) {
+				return
+			}
+		}
+	}
+}
+
+func (enc BinaryEncoding) marshal(record []fieldPair) ([]byte, error) {
+	switch enc {
+	case CBOR:
+		return appendCBOR(nil, record, 0)
+	case MessagePack:
+		return appendMessagePack(nil, record, 0)
+	case BSON:
+		return appendBSONDocument(nil, record, 0)
+	default:
+		return nil, fmt.Errorf("binary encoding %q is not supported", enc)
+	}
+}
+
+func (enc BinaryEncoding) unmarshal(data []byte) ([]fieldPair, error) {
+	var (
+		value any
+		err   error
+	)
+	switch enc {
+	case CBOR:
+		value, err = decodeCBOR(data)
+	case MessagePack:
+		value, err = decodeMessagePack(data)
+	case BSON:
+		value, err = decodeBSON(data)
+	default:
+		return nil, fmt.Errorf("binary encoding %q is not supported", enc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s record: %w", enc, err)
+	}
+
+	record, ok := value.([]fieldPair)
+	if !ok {
+		return nil, fmt.Errorf("decoding %s record: %T is not a document", enc, value)
+	}
+	return record, nil
+}
+
+func binaryRecord(m message.Composer) []fieldPair {
+	content := envelopeContent(m)
+
+	record := []fieldPair{
+		{key: "ts", value: message.Timestamp(m)},
+		{key: "level", value: int64(m.Priority())},
+		{key: "msg", value: content.msg},
+	}
+	if content.err != "" {
+		record = append(record, fieldPair{key: "error", value: content.err})
+	}
+	if len(content.frames) > 0 {
+		record = append(record, fieldPair{key: "stack", value: binaryValue(stackLines(shortStack(content.frames)))})
+	}
+	if len(content.fields) > 0 {
+		fields := make([]fieldPair, len(content.fields))
+		for idx, field := range content.fields {
+			fields[idx] = fieldPair{key: field.key, value: binaryValue(field.value)}
+		}
+		record = append(record, fieldPair{key: "fields", value: fields})
+	}
+	return record
+}
+
+// binaryValue converts values into the types that the binary
+// encodings support: nil, bool, int64, uint64, float64, string,
+// []byte, time.Time, []any, and documents ([]fieldPair.) Other values
+// are converted using their JSON form.
+func binaryValue(value any) any {
+	switch val := value.(type) {
+	case nil, bool, int64, uint64, float64, string, []byte, time.Time:
+		return val
+	case int:
+		return int64(val)
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint:
+		return uint64(val)
+	case uint8:
+		return uint64(val)
+	case uint16:
+		return uint64(val)
+	case uint32:
+		return uint64(val)
+	case float32:
+		return float64(val)
+	case level.Priority:
+		return val.String()
+	case time.Duration:
+		return int64(val)
+	case error:
+		return val.Error()
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		if n, err := val.Float64(); err == nil {
+			return n
+		}
+		return val.String()
+	case []any:
+		out := make([]any, len(val))
+		for idx := range val {
+			out[idx] = binaryValue(val[idx])
+		}
+		return out
+	case []string:
+		out := make([]any, len(val))
+		for idx := range val {
+			out[idx] = val[idx]
+		}
+		return out
+	case []fieldPair:
+		out := make([]fieldPair, len(val))
+		for idx := range val {
+			out[idx] = fieldPair{key: val[idx].key, value: binaryValue(val[idx].value)}
+		}
+		return out
+	case map[string]any:
+		out := make([]fieldPair, 0, len(val))
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			out = append(out, fieldPair{key: key, value: binaryValue(val[key])})
+		}
+		return out
+	case interface{ Iterator() iter.Seq2[string, any] }:
+		var out []fieldPair
+		for key, v := range val.Iterator() {
+			out = append(out, fieldPair{key: key, value: binaryValue(v)})
+		}
+		return out
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return fmt.Sprint(value)
+	}
+	return binaryValue(out)
+}
+
+func decodeBinaryRecord(enc BinaryEncoding, data []byte) (message.Composer, error) {
+	record, err := enc.unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := message.NewKV()
+	var trailer []fieldPair
+	for _, pair := range record {
+		switch pair.key {
+		case "level":
+			p, ok := pair.value.(int64)
+			if !ok {
+				return nil, fmt.Errorf("decoding %s record: level %v is not a number", enc, pair.value)
+			}
+			kv.SetPriority(level.Priority(p))
+		case "msg":
+			kv.KV(message.FieldsMsgName, plainBinaryValue(pair.value))
+		case "fields":
+			fields, ok := pair.value.([]fieldPair)
+			if !ok {
+				return nil, fmt.Errorf("decoding %s record: fields are a %T, not a document", enc, pair.value)
+			}
+			for _, field := range fields {
+				kv.KV(field.key, plainBinaryValue(field.value))
+			}
+		default:
+			trailer = append(trailer, pair)
+		}
+	}
+	for _, pair := range trailer {
+		kv.KV(pair.key, plainBinaryValue(pair.value))
+	}
+	return kv, nil
+}
+
+// plainBinaryValue converts decoded documents into maps.
+func plainBinaryValue(value any) any {
+	switch val := value.(type) {
+	case []fieldPair:
+		out := make(map[string]any, len(val))
+		for _, pair := range val {
+			out[pair.key] = plainBinaryValue(pair.value)
+		}
+		return out
+	case []any:
+		for idx := range val {
+			val[idx] = plainBinaryValue(val[idx])
+		}
+		return val
+	default:
+		return val
+	}
+}
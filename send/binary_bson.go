@@ -0,0 +1,223 @@
+package send
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BSON support for binary logs. Integers that fit are encoded as
+// int32 values, unsigned integers that overflow int64 as doubles, and
+// times as UTC datetimes, with millisecond precision.
+//
+// The codecs for the binary encodings are written here, rather than
+// using birch (as x/metrics does) or another library: the root module
+// depends only on fun, and the binary logs need only the small subset
+// of each encoding that the record values use.
+
+func appendBSONDocument(buf []byte, doc []fieldPair, depth int) ([]byte, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("bson: value is too deeply nested")
+	}
+
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+
+	var err error
+	for _, pair := range doc {
+		if buf, err = appendBSONElement(buf, pair.key, pair.value, depth); err != nil {
+			return nil, err
+		}
+	}
+	buf = append(buf, 0)
+
+	if len(buf)-start > math.MaxInt32 {
+		return nil, errors.New("bson: document is too large")
+	}
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	return buf, nil
+}
+
+func appendBSONElement(buf []byte, key string, value any, depth int) ([]byte, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return nil, fmt.Errorf("bson: key %q contains a null byte", key)
+	}
+	head := func(kind byte) []byte { return append(append(append(buf, kind), key...), 0) }
+
+	switch val := value.(type) {
+	case nil:
+		return head(0x0a), nil
+	case bool:
+		if val {
+			return append(head(0x08), 1), nil
+		}
+		return append(head(0x08), 0), nil
+	case int64:
+		if val >= math.MinInt32 && val <= math.MaxInt32 {
+			return binary.LittleEndian.AppendUint32(head(0x10), uint32(val)), nil
+		}
+		return binary.LittleEndian.AppendUint64(head(0x12), uint64(val)), nil
+	case uint64:
+		if val > math.MaxInt64 {
+			return appendBSONElement(buf, key, float64(val), depth)
+		}
+		return appendBSONElement(buf, key, int64(val), depth)
+	case float64:
+		return binary.LittleEndian.AppendUint64(head(0x01), math.Float64bits(val)), nil
+	case string:
+		if len(val) >= math.MaxInt32 {
+			return nil, errors.New("bson: string is too long")
+		}
+		out := binary.LittleEndian.AppendUint32(head(0x02), uint32(len(val)+1))
+		return append(append(out, val...), 0), nil
+	case []byte:
+		if len(val) > math.MaxInt32 {
+			return nil, errors.New("bson: binary value is too long")
+		}
+		out := binary.LittleEndian.AppendUint32(head(0x05), uint32(len(val)))
+		return append(append(out, 0x00), val...), nil
+	case time.Time:
+		return binary.LittleEndian.AppendUint64(head(0x09), uint64(val.UnixMilli())), nil
+	case []any:
+		doc := make([]fieldPair, len(val))
+		for idx := range val {
+			doc[idx] = fieldPair{key: strconv.Itoa(idx), value: val[idx]}
+		}
+		return appendBSONDocument(head(0x04), doc, depth+1)
+	case []fieldPair:
+		return appendBSONDocument(head(0x03), val, depth+1)
+	default:
+		return nil, fmt.Errorf("bson: %T values are not supported", value)
+	}
+}
+
+func decodeBSON(data []byte) (any, error) {
+	doc, rest, err := readBSONDocument(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("bson: %d trailing bytes", len(rest))
+	}
+	return doc, nil
+}
+
+// readBSONDocument reads the document at the beginning of the data,
+// and returns the remainder of the data.
+func readBSONDocument(data []byte, depth int) ([]fieldPair, []byte, error) {
+	if depth > maxBinaryDepth {
+		return nil, nil, errors.New("bson: value is too deeply nested")
+	}
+	if len(data) < 5 {
+		return nil, nil, errors.New("bson: unexpected end of data")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if size < 5 || uint64(size) > uint64(len(data)) || data[size-1] != 0 {
+		return nil, nil, fmt.Errorf("bson: invalid document length %d", size)
+	}
+	body, rest := data[4:size-1], data[size:]
+
+	var doc []fieldPair
+	for len(body) > 0 {
+		kind := body[0]
+		end := bytes.IndexByte(body[1:], 0)
+		if end < 0 {
+			return nil, nil, errors.New("bson: unterminated key")
+		}
+		key := string(body[1 : end+1])
+		body = body[end+2:]
+
+		var (
+			value any
+			err   error
+		)
+		if value, body, err = readBSONValue(kind, body, depth); err != nil {
+			return nil, nil, fmt.Errorf("bson: %q: %w", key, err)
+		}
+		doc = append(doc, fieldPair{key: key, value: value})
+	}
+	return doc, rest, nil
+}
+
+func readBSONValue(kind byte, data []byte, depth int) (any, []byte, error) {
+	fixed := func(size int) ([]byte, []byte, error) {
+		if len(data) < size {
+			return nil, nil, errors.New("unexpected end of data")
+		}
+		return data[:size], data[size:], nil
+	}
+
+	switch kind {
+	case 0x0a:
+		return nil, data, nil
+	case 0x08:
+		val, rest, err := fixed(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return val[0] != 0, rest, nil
+	case 0x10:
+		val, rest, err := fixed(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(int32(binary.LittleEndian.Uint32(val))), rest, nil
+	case 0x12:
+		val, rest, err := fixed(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(binary.LittleEndian.Uint64(val)), rest, nil
+	case 0x01:
+		val, rest, err := fixed(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(val)), rest, nil
+	case 0x09:
+		val, rest, err := fixed(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return time.UnixMilli(int64(binary.LittleEndian.Uint64(val))), rest, nil
+	case 0x02:
+		val, rest, err := fixed(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		size := binary.LittleEndian.Uint32(val)
+		if size < 1 || uint64(size) > uint64(len(rest)) || rest[size-1] != 0 {
+			return nil, nil, fmt.Errorf("invalid string length %d", size)
+		}
+		return string(rest[:size-1]), rest[size:], nil
+	case 0x05:
+		val, rest, err := fixed(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		size := binary.LittleEndian.Uint32(val)
+		if uint64(size) > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("invalid binary length %d", size)
+		}
+		return append([]byte{}, rest[:size]...), rest[size:], nil
+	case 0x03:
+		return readBSONDocument(data, depth+1)
+	case 0x04:
+		doc, rest, err := readBSONDocument(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		out := make([]any, len(doc))
+		for idx := range doc {
+			out[idx] = doc[idx].value
+		}
+		return out, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("type 0x%02x is not supported", kind)
+	}
+}
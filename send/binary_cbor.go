@@ -0,0 +1,265 @@
+package send
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CBOR (RFC 8949) support for binary logs. Times are encoded as
+// RFC 3339 strings (tag 0,) and documents as maps with string keys.
+
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+func appendCBOR(buf []byte, value any, depth int) ([]byte, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("cbor: value is too deeply nested")
+	}
+
+	var err error
+	switch val := value.(type) {
+	case nil:
+		return append(buf, 0xf6), nil
+	case bool:
+		if val {
+			return append(buf, 0xf5), nil
+		}
+		return append(buf, 0xf4), nil
+	case int64:
+		if val < 0 {
+			return appendCBORHead(buf, 1, uint64(-(val + 1))), nil
+		}
+		return appendCBORHead(buf, 0, uint64(val)), nil
+	case uint64:
+		return appendCBORHead(buf, 0, val), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(val)), nil
+	case string:
+		return append(appendCBORHead(buf, 3, uint64(len(val))), val...), nil
+	case []byte:
+		return append(appendCBORHead(buf, 2, uint64(len(val))), val...), nil
+	case time.Time:
+		text := val.Format(time.RFC3339Nano)
+		return append(appendCBORHead(appendCBORHead(buf, 6, 0), 3, uint64(len(text))), text...), nil
+	case []any:
+		buf = appendCBORHead(buf, 4, uint64(len(val)))
+		for idx := range val {
+			if buf, err = appendCBOR(buf, val[idx], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case []fieldPair:
+		buf = appendCBORHead(buf, 5, uint64(len(val)))
+		for _, pair := range val {
+			buf = append(appendCBORHead(buf, 3, uint64(len(pair.key))), pair.key...)
+			if buf, err = appendCBOR(buf, pair.value, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("cbor: %T values are not supported", value)
+	}
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func decodeCBOR(data []byte) (any, error) {
+	dec := &cborDecoder{data: data}
+	value, err := dec.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if dec.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(data)-dec.pos)
+	}
+	return value, nil
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("cbor: unexpected end of data")
+	}
+	out := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return out, nil
+}
+
+// argument reads the argument of the head, which for major type 7 is
+// the value itself.
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		data, err := d.next(1 << (info - 24))
+		if err != nil {
+			return 0, err
+		}
+		var out uint64
+		for _, b := range data {
+			out = out<<8 | uint64(b)
+		}
+		return out, nil
+	case info == 31:
+		return 0, errors.New("cbor: indefinite length items are not supported")
+	default:
+		return 0, fmt.Errorf("cbor: invalid additional information %d", info)
+	}
+}
+
+func (d *cborDecoder) value(depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("cbor: value is too deeply nested")
+	}
+
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := head[0]>>5, head[0]&0x1f
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		data, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, data...), nil
+	case 3:
+		data, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.New("cbor: unexpected end of data")
+		}
+		out := make([]any, arg)
+		for idx := range out {
+			if out[idx], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errors.New("cbor: unexpected end of data")
+		}
+		out := make([]fieldPair, arg)
+		for idx := range out {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("cbor: %T map keys are not supported", key)
+			}
+			value, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out[idx] = fieldPair{key: name, value: value}
+		}
+		return out, nil
+	case 6:
+		value, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case arg == 0:
+			text, ok := value.(string)
+			if !ok {
+				return nil, errors.New("cbor: date/time string is not a string")
+			}
+			return time.Parse(time.RFC3339Nano, text)
+		case arg == 1:
+			switch epoch := value.(type) {
+			case int64:
+				return time.Unix(epoch, 0), nil
+			case float64:
+				sec, frac := math.Modf(epoch)
+				return time.Unix(int64(sec), int64(frac*1e9)), nil
+			}
+			return nil, errors.New("cbor: epoch date/time is not a number")
+		}
+		// other tags are not interpreted.
+		return value, nil
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return halfFloat(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+		return nil, fmt.Errorf("cbor: simple value %d is not supported", arg)
+	}
+}
+
+// halfFloat converts IEEE 754 half precision values.
+func halfFloat(bits uint16) float64 {
+	exp, mant := int(bits>>10)&0x1f, float64(bits&0x3ff)
+	var out float64
+	switch exp {
+	case 0:
+		out = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			out = math.Inf(1)
+		} else {
+			out = math.NaN()
+		}
+	default:
+		out = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		return -out
+	}
+	return out
+}
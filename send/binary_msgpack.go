@@ -0,0 +1,326 @@
+package send
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MessagePack support for binary logs. Times use the timestamp
+// extension type (-1,) in the 96 bit form.
+
+const msgpackTimestamp = 0xff // -1, the timestamp extension type
+
+// appendMessagePackLength writes the head of strings, arrays, and
+// maps: the fixed form holds lengths up to fixMax, and the 8 bit form
+// is only available when head8 is non-zero.
+func appendMessagePackLength(buf []byte, n int, fix byte, fixMax int, head8, head16, head32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(buf, fix|byte(n))
+	case head8 != 0 && n <= math.MaxUint8:
+		return append(buf, head8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, head16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, head32), uint32(n))
+	}
+}
+
+func appendMessagePack(buf []byte, value any, depth int) ([]byte, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("msgpack: value is too deeply nested")
+	}
+
+	var err error
+	switch val := value.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if val {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int64:
+		switch {
+		case val >= 0:
+			return appendMessagePack(buf, uint64(val), depth)
+		case val >= -32:
+			return append(buf, byte(val)), nil
+		case val >= math.MinInt8:
+			return append(buf, 0xd0, byte(val)), nil
+		case val >= math.MinInt16:
+			return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(val)), nil
+		case val >= math.MinInt32:
+			return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(val)), nil
+		default:
+			return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(val)), nil
+		}
+	case uint64:
+		switch {
+		case val <= 0x7f:
+			return append(buf, byte(val)), nil
+		case val <= math.MaxUint8:
+			return append(buf, 0xcc, byte(val)), nil
+		case val <= math.MaxUint16:
+			return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(val)), nil
+		case val <= math.MaxUint32:
+			return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(val)), nil
+		default:
+			return binary.BigEndian.AppendUint64(append(buf, 0xcf), val), nil
+		}
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(val)), nil
+	case string:
+		if uint64(len(val)) > math.MaxUint32 {
+			return nil, errors.New("msgpack: string is too long")
+		}
+		return append(appendMessagePackLength(buf, len(val), 0xa0, 31, 0xd9, 0xda, 0xdb), val...), nil
+	case []byte:
+		if uint64(len(val)) > math.MaxUint32 {
+			return nil, errors.New("msgpack: binary value is too long")
+		}
+		return append(appendMessagePackLength(buf, len(val), 0, -1, 0xc4, 0xc5, 0xc6), val...), nil
+	case time.Time:
+		buf = append(buf, 0xc7, 12, msgpackTimestamp)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(val.Nanosecond()))
+		return binary.BigEndian.AppendUint64(buf, uint64(val.Unix())), nil
+	case []any:
+		buf = appendMessagePackLength(buf, len(val), 0x90, 15, 0, 0xdc, 0xdd)
+		for idx := range val {
+			if buf, err = appendMessagePack(buf, val[idx], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case []fieldPair:
+		buf = appendMessagePackLength(buf, len(val), 0x80, 15, 0, 0xde, 0xdf)
+		for _, pair := range val {
+			if buf, err = appendMessagePack(buf, pair.key, depth+1); err != nil {
+				return nil, err
+			}
+			if buf, err = appendMessagePack(buf, pair.value, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("msgpack: %T values are not supported", value)
+	}
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func decodeMessagePack(data []byte) (any, error) {
+	dec := &msgpackDecoder{data: data}
+	value, err := dec.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if dec.pos != len(data) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", len(data)-dec.pos)
+	}
+	return value, nil
+}
+
+func (d *msgpackDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("msgpack: unexpected end of data")
+	}
+	out := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return out, nil
+}
+
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	data, err := d.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var out uint64
+	for _, b := range data {
+		out = out<<8 | uint64(b)
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("msgpack: value is too deeply nested")
+	}
+
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b := head[0]; {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return d.str(uint64(b & 0x1f))
+	case b&0xf0 == 0x90:
+		return d.array(uint64(b&0x0f), depth)
+	case b&0xf0 == 0x80:
+		return d.document(uint64(b&0x0f), depth)
+	}
+
+	switch b := head[0]; b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, data...), nil
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.document(n, depth)
+	case 0xd6, 0xd7, 0xd8, 0xc7, 0xc8, 0xc9:
+		return d.extension(b)
+	default:
+		return nil, fmt.Errorf("msgpack: invalid type 0x%02x", b)
+	}
+}
+
+func (d *msgpackDecoder) str(n uint64) (any, error) {
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (d *msgpackDecoder) array(n uint64, depth int) (any, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("msgpack: unexpected end of data")
+	}
+	out := make([]any, n)
+	var err error
+	for idx := range out {
+		if out[idx], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) document(n uint64, depth int) (any, error) {
+	if n > uint64(len(d.data)-d.pos)/2 {
+		return nil, errors.New("msgpack: unexpected end of data")
+	}
+	out := make([]fieldPair, n)
+	for idx := range out {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: %T map keys are not supported", key)
+		}
+		value, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[idx] = fieldPair{key: name, value: value}
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) extension(b byte) (any, error) {
+	var (
+		size uint64
+		err  error
+	)
+	switch b {
+	case 0xd6, 0xd7, 0xd8:
+		size = 4 << (b - 0xd6)
+	default:
+		if size, err = d.uint(1 << (b - 0xc7)); err != nil {
+			return nil, err
+		}
+	}
+
+	kind, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.next(size)
+	if err != nil {
+		return nil, err
+	}
+	if kind[0] != msgpackTimestamp {
+		return nil, fmt.Errorf("msgpack: extension type %d is not supported", int8(kind[0]))
+	}
+
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0), nil
+	case 8:
+		n := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))), nil
+	default:
+		return nil, fmt.Errorf("msgpack: invalid timestamp of %d bytes", len(data))
+	}
+}
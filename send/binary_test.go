@@ -0,0 +1,336 @@
+package send
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+var binaryEncodings = []BinaryEncoding{CBOR, MessagePack, BSON}
+
+func TestBinaryCodecs(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC)
+
+	values := []any{
+		nil, true, false,
+		int64(0), int64(1), int64(127), int64(128), int64(255), int64(256), int64(65536),
+		int64(-1), int64(-32), int64(-33), int64(-129), int64(-32769), int64(math.MinInt64), int64(math.MaxInt64),
+		1.5, -0.25, math.Inf(1),
+		"", "short", strings.Repeat("x", 31), strings.Repeat("x", 32), strings.Repeat("x", 256), strings.Repeat("x", 70000),
+		[]byte{}, []byte("bytes"), bytes.Repeat([]byte{1}, 300),
+		[]any{}, []any{int64(1), "two", []any{3.5}},
+		[]fieldPair{{key: "nested", value: []fieldPair{{key: "b", value: int64(1)}, {key: "a", value: nil}}}},
+	}
+	long := make([]any, 20)
+	for idx := range long {
+		long[idx] = int64(idx)
+	}
+	values = append(values, long)
+
+	for _, enc := range binaryEncodings {
+		t.Run(string(enc), func(t *testing.T) {
+			for idx, value := range values {
+				record := []fieldPair{{key: "v", value: value}, {key: "ts", value: ts}}
+				data, err := enc.marshal(record)
+				check.NotError(t, err)
+				out, err := enc.unmarshal(data)
+				check.NotError(t, err)
+				check.Equal(t, len(out), 2)
+				check.Equal(t, out[0].key, "v")
+				check.True(t, out[1].value.(time.Time).Equal(ts))
+				if !equalBinaryValues(value, out[0].value) {
+					t.Errorf("value %d: %v != %v", idx, value, out[0].value)
+				}
+			}
+
+			big := []fieldPair{{key: "v", value: uint64(math.MaxUint64)}}
+			data, err := enc.marshal(big)
+			check.NotError(t, err)
+			out, err := enc.unmarshal(data)
+			check.NotError(t, err)
+			if enc == BSON {
+				check.Equal(t, out[0].value, any(float64(math.MaxUint64)))
+			} else {
+				check.Equal(t, out[0].value, any(uint64(math.MaxUint64)))
+			}
+
+			_, err = enc.unmarshal(data[:len(data)-1])
+			check.Error(t, err)
+			_, err = enc.unmarshal(append(data, 0))
+			check.Error(t, err)
+		})
+	}
+
+	t.Run("Vectors", func(t *testing.T) {
+		for _, tc := range []struct {
+			enc    BinaryEncoding
+			record []fieldPair
+			data   []byte
+		}{
+			{CBOR, []fieldPair{{key: "a", value: int64(100)}, {key: "b", value: int64(-1000)}},
+				[]byte{0xa2, 0x61, 'a', 0x18, 0x64, 0x61, 'b', 0x39, 0x03, 0xe7}},
+			{MessagePack, []fieldPair{{key: "a", value: int64(-33)}, {key: "b", value: true}},
+				[]byte{0x82, 0xa1, 'a', 0xd0, 0xdf, 0xa1, 'b', 0xc3}},
+			{BSON, []fieldPair{{key: "hello", value: "world"}},
+				[]byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00")},
+		} {
+			data, err := tc.enc.marshal(tc.record)
+			check.NotError(t, err)
+			check.True(t, bytes.Equal(data, tc.data))
+		}
+
+		// a CBOR epoch time and a half precision float
+		out, err := CBOR.unmarshal([]byte{0xa2, 0x61, 't', 0xc1, 0x1a, 0x65, 0x94, 0x25, 0x45, 0x61, 'f', 0xf9, 0x3e, 0x00})
+		check.NotError(t, err)
+		check.True(t, out[0].value.(time.Time).Equal(time.Unix(1704207685, 0)))
+		check.Equal(t, out[1].value, any(1.5))
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := BSON.marshal([]fieldPair{{key: "nul\x00", value: int64(1)}})
+		check.Error(t, err)
+		_, err = BinaryEncoding("xml").marshal(nil)
+		check.Error(t, err)
+		_, err = CBOR.unmarshal([]byte{0x01})
+		check.Error(t, err)
+		_, err = MessagePack.unmarshal([]byte{0xc1})
+		check.Error(t, err)
+		_, err = BSON.unmarshal([]byte{0x05, 0x00, 0x00, 0x00, 0x01})
+		check.Error(t, err)
+	})
+}
+
+func equalBinaryValues(a, b any) bool {
+	switch av := a.(type) {
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && bytes.Equal(av, bv)
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for idx := range av {
+			if !equalBinaryValues(av[idx], bv[idx]) {
+				return false
+			}
+		}
+		return true
+	case []fieldPair:
+		bv, ok := b.([]fieldPair)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for idx := range av {
+			if av[idx].key != bv[idx].key || !equalBinaryValues(av[idx].value, bv[idx].value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func TestBinaryLog(t *testing.T) {
+	for _, enc := range binaryEncodings {
+		t.Run(string(enc), func(t *testing.T) {
+			var buf bytes.Buffer
+			s := MakeBinaryWriter(&buf, enc)
+			s.SetPriority(level.Debug)
+
+			kv := message.NewKV().
+				KV("msg", "request complete").
+				KV("status", 200).
+				KV("ratio", 0.5).
+				KV("tags", []string{"a", "b"}).
+				KV("doc", map[string]any{"nested": true}).
+				KV("since", time.Second)
+			kv.SetPriority(level.Warning)
+			s.Send(kv)
+
+			s.Send(message.BuildGroupComposer(NewString(level.Info, "one"), NewString(level.Info, "two")))
+
+			failure := message.MakeError(errors.New("connection refused"))
+			failure.SetPriority(level.Error)
+			failure.SetOption(message.OptionCollectInfo)
+			s.Send(failure)
+
+			stack := message.MakeStack(1, "captured")
+			stack.SetPriority(level.Debug)
+			s.Send(stack)
+
+			s.Send(NewString(level.Trace, "dropped"))
+			check.NotError(t, s.Close())
+
+			var msgs []message.Composer
+			for m, err := range ReadBinaryLog(&buf, enc) {
+				check.NotError(t, err)
+				msgs = append(msgs, m)
+			}
+			check.Equal(t, len(msgs), 5)
+
+			check.Equal(t, msgs[0].Priority(), level.Warning)
+			fields := binaryLogFields(msgs[0])
+			check.Equal(t, fields["msg"], any("request complete"))
+			check.Equal(t, fields["status"], any(int64(200)))
+			check.Equal(t, fields["ratio"], any(0.5))
+			check.Equal(t, len(fields["tags"].([]any)), 2)
+			check.Equal(t, fields["doc"].(map[string]any)["nested"], any(true))
+			check.Equal(t, fields["since"], any(int64(time.Second)))
+			check.True(t, time.Since(fields["ts"].(time.Time)) < time.Minute)
+
+			check.Equal(t, binaryLogFields(msgs[1])["msg"], any("one"))
+			check.Equal(t, binaryLogFields(msgs[2])["msg"], any("two"))
+
+			check.Equal(t, msgs[3].Priority(), level.Error)
+			check.Equal(t, binaryLogFields(msgs[3])["error"], any("connection refused"))
+			want := message.Timestamp(failure)
+			if enc == BSON {
+				want = time.UnixMilli(want.UnixMilli())
+			}
+			check.True(t, binaryLogFields(msgs[3])["ts"].(time.Time).Equal(want))
+
+			frames := binaryLogFields(msgs[4])["stack"].([]any)
+			check.True(t, len(frames) > 1)
+			check.True(t, strings.HasPrefix(frames[0].(string), "send/binary_test.go:"))
+		})
+	}
+
+	t.Run("Corrupt", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := MakeBinaryWriter(&buf, CBOR)
+		s.SetPriority(level.Info)
+		s.Send(NewString(level.Info, "first"))
+		size := buf.Len()
+		buf.Write([]byte{0, 0, 0, 1, 0xff})
+		s.Send(NewString(level.Info, "last"))
+
+		var (
+			msgs []message.Composer
+			errs []error
+		)
+		for m, err := range ReadBinaryLog(bytes.NewReader(buf.Bytes()), CBOR) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			msgs = append(msgs, m)
+		}
+		check.Equal(t, len(msgs), 2)
+		check.Equal(t, len(errs), 1)
+
+		msgs, errs = nil, nil
+		for m, err := range ReadBinaryLog(bytes.NewReader(buf.Bytes()[:size-1]), CBOR) {
+			msgs = append(msgs, m)
+			errs = append(errs, err)
+		}
+		check.Equal(t, len(errs), 1)
+		check.Error(t, errs[0])
+
+		header := binary.BigEndian.AppendUint32(nil, maxBinaryRecordSize+1)
+		for _, err := range ReadBinaryLog(bytes.NewReader(header), CBOR) {
+			check.Error(t, err)
+		}
+	})
+	t.Run("Spec", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "log.msgpack")
+		s, err := BuildJSON([]byte(`{"type":"binary","priority":"info","options":{"path":"` + path + `","encoding":"msgpack"}}`))
+		check.NotError(t, err)
+		s.Send(NewString(level.Info, "hello"))
+		check.NotError(t, s.Close())
+
+		data, err := os.ReadFile(path)
+		check.NotError(t, err)
+		var count int
+		for m, err := range ReadBinaryLog(bytes.NewReader(data), MessagePack) {
+			check.NotError(t, err)
+			check.Equal(t, binaryLogFields(m)["msg"], any("hello"))
+			count++
+		}
+		check.Equal(t, count, 1)
+
+		for _, opts := range []string{`{}`, `{"path":"x","encoding":"xml"}`} {
+			_, err = BuildJSON([]byte(`{"type":"binary","options":` + opts + `}`))
+			check.Error(t, err)
+		}
+	})
+}
+
+func binaryLogFields(m message.Composer) map[string]any {
+	out := map[string]any{}
+	for key, value := range m.Raw().(interface{ Iterator() iter.Seq2[string, any] }).Iterator() {
+		out[key] = value
+	}
+	return out
+}
+
+// fuzzBinaryDecoder checks that the decoder rejects or accepts
+// arbitrary input without panicking, and that the documents it
+// accepts can be encoded and decoded again.
+func fuzzBinaryDecoder(f *testing.F, enc BinaryEncoding, decode func([]byte) (any, error)) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	m := message.WrapStack(1, message.Fields{"msg": "seed", "n": 42, "tags": []string{"a", "b"}})
+	m.SetPriority(level.Error)
+	for _, record := range [][]fieldPair{
+		binaryRecord(m),
+		{{key: "ts", value: ts}, {key: "v", value: []any{int64(-1), uint64(math.MaxUint64), 1.5, []byte("raw"), nil, true}}},
+		{{key: "nested", value: []fieldPair{{key: "a", value: []fieldPair{{key: "b", value: "c"}}}}}},
+	} {
+		data, err := enc.marshal(record)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := decode(data)
+		if err != nil {
+			return
+		}
+		record, ok := value.([]fieldPair)
+		if !ok {
+			return
+		}
+		out, err := enc.marshal(record)
+		if err != nil {
+			// decoded documents may hold keys or values that the
+			// encoding cannot write back (e.g. BSON keys with NUL.)
+			return
+		}
+		again, err := enc.unmarshal(out)
+		if err != nil {
+			t.Fatalf("re-encoded %s record does not decode: %v", enc, err)
+		}
+		if len(again) != len(record) {
+			t.Fatalf("re-encoded %s record has %d members, not %d", enc, len(again), len(record))
+		}
+	})
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	f.Add([]byte{0xa2, 0x61, 't', 0xc1, 0x1a, 0x65, 0x94, 0x25, 0x45, 0x61, 'f', 0xf9, 0x3e, 0x00})
+	fuzzBinaryDecoder(f, CBOR, decodeCBOR)
+}
+
+func FuzzDecodeMessagePack(f *testing.F) {
+	f.Add([]byte{0x82, 0xa1, 'a', 0xd0, 0xdf, 0xa1, 'b', 0xc3})
+	fuzzBinaryDecoder(f, MessagePack, decodeMessagePack)
+}
+
+func FuzzDecodeBSON(f *testing.F) {
+	f.Add([]byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00"))
+	fuzzBinaryDecoder(f, BSON, decodeBSON)
+}
@@ -0,0 +1,162 @@
+package send
+
+import (
+	"io"
+	"iter"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tychoish/grip/message"
+)
+
+// DefaultConsoleTimeLayout is the layout of the timestamps produced
+// by console formatters that do not specify a layout.
+const DefaultConsoleTimeLayout = "15:04:05.000"
+
+// ConsoleOptions configure console formatters.
+type ConsoleOptions struct {
+	// Color enables ANSI color escape sequences. Use ColorEnabled
+	// to decide if a writer supports colors.
+	Color bool
+	// TimeLayout is the layout of the timestamp at the beginning
+	// of each line, and defaults to DefaultConsoleTimeLayout. Use
+	// "-" to omit the timestamp.
+	TimeLayout string
+
+	timestamp func(message.Composer) time.Time
+}
+
+// ColorEnabled reports if output to the writer should be colorized:
+// writers must be terminals (character devices, as with os.Stdout
+// and os.Stderr when they are not redirected,) and colors are
+// disabled when the NO_COLOR environment variable is set (see
+// https://no-color.org) or TERM is "dumb".
+func ColorEnabled(wr io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	file, ok := wr.(*os.File)
+	if !ok || file == nil {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// MakeConsoleFormatter returns a MessageFormatter for human readable
+// output, as in:
+//
+//	15:04:05.000 WARN disk almost full path=/var used=91%
+//
+// Lines begin with the message's timestamp (see message.Timestamp)
+// and the first four letters of the priority, followed by the message and the message's fields, as
+// with MakeLogfmtFormatter. Subsequent lines of multiline messages
+// and the frames of stack messages (see message.MakeStack) are
+// indented on the following lines.
+//
+// With colors, the timestamp is dimmed, the priority is colored by
+// level, and the keys of fields are highlighted. Without colors the
+// output is plain text, and (but for the timestamp) deterministic.
+// The formatter never errors.
+func MakeConsoleFormatter(opts ConsoleOptions) MessageFormatter {
+	if opts.TimeLayout == "" {
+		opts.TimeLayout = DefaultConsoleTimeLayout
+	}
+	if opts.timestamp == nil {
+		opts.timestamp = message.Timestamp
+	}
+
+	return func(m message.Composer) (string, error) {
+		var buf strings.Builder
+
+		if opts.TimeLayout != "-" {
+			opts.colorize(&buf, ansiColors["dim"], opts.timestamp(m).Format(opts.TimeLayout))
+			buf.WriteByte(' ')
+		}
+
+		label := strings.ToUpper(m.Priority().String())
+		if utf8.RuneCountInString(label) > 4 {
+			label = string([]rune(label)[:4])
+		}
+		opts.colorize(&buf, levelColor(m.Priority()), label+strings.Repeat(" ", 4-utf8.RuneCountInString(label)))
+
+		var (
+			msg    string
+			fields iter.Seq2[string, any]
+			frames message.StackFrames
+		)
+		if trace, ok := m.Raw().(message.StackTrace); ok {
+			// the string form of stack messages begins with
+			// the frames, which follow the message instead.
+			frames = trace.Frames
+			msg = strings.TrimPrefix(m.String(), frames.String())
+			fields = func(func(string, any) bool) {}
+		} else {
+			msg, fields = logfmtFields(m)
+		}
+
+		lines := strings.Split(strings.TrimSpace(msg), "\n")
+		if lines[0] != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(lines[0])
+		}
+
+		for key, value := range fields {
+			if stack, ok := value.(message.StackFrames); ok {
+				frames = stack
+				continue
+			}
+			buf.WriteByte(' ')
+			if opts.Color {
+				buf.WriteString(ansiColors["cyan"])
+			}
+			writeLogfmtKey(&buf, key)
+			buf.WriteByte('=')
+			if opts.Color {
+				buf.WriteString(ansiReset)
+			}
+			writeLogfmtString(&buf, logfmtValue(value))
+		}
+
+		for _, line := range lines[1:] {
+			buf.WriteString("\n    ")
+			buf.WriteString(line)
+		}
+		for _, frame := range frames {
+			buf.WriteString("\n    ")
+			opts.colorize(&buf, ansiColors["dim"], frame.String())
+		}
+
+		return buf.String(), nil
+	}
+}
+
+func (opts ConsoleOptions) colorize(buf *strings.Builder, color, value string) {
+	if !opts.Color {
+		buf.WriteString(value)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(value)
+	buf.WriteString(ansiReset)
+}
+
+// MakeConsole returns a sender that writes messages to the writer
+// using a console formatter, with colors when ColorEnabled reports
+// that the writer supports them.
+func MakeConsole(wr io.Writer) Sender {
+	s := MakeWriter(wr)
+	s.SetFormatter(MakeConsoleFormatter(ConsoleOptions{Color: ColorEnabled(wr)}))
+	return s
+}
+
+// MakeConsoleStdOutput returns a console sender (see MakeConsole)
+// that writes to standard output.
+func MakeConsoleStdOutput() Sender { return MakeConsole(os.Stdout) }
+
+// MakeConsoleStdError returns a console sender (see MakeConsole)
+// that writes to standard error.
+func MakeConsoleStdError() Sender { return MakeConsole(os.Stderr) }
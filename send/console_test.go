@@ -0,0 +1,116 @@
+package send
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestConsoleFormatter(t *testing.T) {
+	clock := func() time.Time { return time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC) }
+	format := func(t *testing.T, opts ConsoleOptions, m message.Composer) string {
+		t.Helper()
+		opts.timestamp = func(message.Composer) time.Time { return clock() }
+		out, err := MakeConsoleFormatter(opts)(m)
+		check.NotError(t, err)
+		return out
+	}
+
+	kv := message.NewKV().KV("msg", "disk almost full").KV("path", "/var").KV("note", "two words")
+	kv.SetPriority(level.Warning)
+
+	t.Run("Plain", func(t *testing.T) {
+		check.Equal(t, format(t, ConsoleOptions{}, kv), `15:04:05.123 WARN disk almost full path=/var note="two words"`)
+		check.Equal(t, format(t, ConsoleOptions{TimeLayout: "-"}, NewString(level.Info, "hello")), "INFO hello")
+		check.Equal(t, format(t, ConsoleOptions{TimeLayout: time.DateTime}, NewString(level.Emergency, "oh no")),
+			"2024-01-02 15:04:05 EMER oh no")
+	})
+	t.Run("Color", func(t *testing.T) {
+		check.Equal(t, format(t, ConsoleOptions{Color: true}, kv),
+			"\x1b[2m15:04:05.123\x1b[0m \x1b[33mWARN\x1b[0m disk almost full "+
+				"\x1b[36mpath=\x1b[0m/var \x1b[36mnote=\x1b[0m\"two words\"")
+		check.Equal(t, format(t, ConsoleOptions{Color: true, TimeLayout: "-"}, NewString(level.Error, "failed")),
+			"\x1b[31mERRO\x1b[0m failed")
+	})
+	t.Run("Multiline", func(t *testing.T) {
+		m := message.MakeError(errors.New("first\nsecond\nthird"))
+		m.SetPriority(level.Error)
+		check.Equal(t, format(t, ConsoleOptions{TimeLayout: "-"}, m), "ERRO first\n    second\n    third")
+	})
+	t.Run("Stack", func(t *testing.T) {
+		m := message.MakeStack(1, "captured")
+		m.SetPriority(level.Debug)
+		lines := strings.Split(format(t, ConsoleOptions{TimeLayout: "-"}, m), "\n")
+		check.Equal(t, lines[0], "DEBU captured")
+		check.True(t, len(lines) > 1)
+		check.True(t, strings.HasPrefix(lines[1], "    "))
+		check.Substring(t, lines[1], "console_test.go")
+
+		structured := message.WrapStack(1, message.NewKV().KV("msg", "structured").KV("id", 42))
+		structured.SetPriority(level.Info)
+		lines = strings.Split(format(t, ConsoleOptions{TimeLayout: "-"}, structured), "\n")
+		check.Equal(t, lines[0], "INFO structured id=42")
+		check.True(t, len(lines) > 1)
+		check.Substring(t, lines[1], "console_test.go")
+	})
+	t.Run("Writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := MakeConsole(&buf)
+		s.SetPriority(level.Info)
+		s.Send(NewString(level.Info, "hello"))
+		check.True(t, strings.HasSuffix(buf.String(), " INFO hello\n"))
+		check.True(t, !strings.Contains(buf.String(), "\x1b["))
+	})
+	t.Run("Timestamp", func(t *testing.T) {
+		m := message.MakeString("collected")
+		m.SetPriority(level.Info)
+		m.SetOption(message.OptionCollectInfo)
+		out, err := MakeConsoleFormatter(ConsoleOptions{TimeLayout: time.RFC3339Nano})(m)
+		check.NotError(t, err)
+		check.Equal(t, out, message.Timestamp(m).Format(time.RFC3339Nano)+" INFO collected")
+	})
+	t.Run("Spec", func(t *testing.T) {
+		s, err := BuildJSON([]byte(`{"type":"console","options":{"output":"stderr","color":"always","time_layout":"-"}}`))
+		check.NotError(t, err)
+		out, err := s.GetFormatter()(NewString(level.Info, "hi"))
+		check.NotError(t, err)
+		check.Equal(t, out, "\x1b[32mINFO\x1b[0m hi")
+
+		for _, opts := range []string{`{"output":"file"}`, `{"color":"sometimes"}`} {
+			_, err = BuildJSON([]byte(`{"type":"console","options":` + opts + `}`))
+			check.Error(t, err)
+		}
+	})
+}
+
+func TestColorEnabled(t *testing.T) {
+	devnull, err := os.Open(os.DevNull)
+	check.NotError(t, err)
+	defer devnull.Close()
+
+	rd, wr, err := os.Pipe()
+	check.NotError(t, err)
+	defer rd.Close()
+	defer wr.Close()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	check.True(t, ColorEnabled(devnull))
+	check.True(t, !ColorEnabled(wr))
+	check.True(t, !ColorEnabled(&bytes.Buffer{}))
+	check.True(t, !ColorEnabled((*os.File)(nil)))
+
+	t.Setenv("TERM", "dumb")
+	check.True(t, !ColorEnabled(devnull))
+
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "1")
+	check.True(t, !ColorEnabled(devnull))
+}
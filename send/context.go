@@ -0,0 +1,31 @@
+package send
+
+import (
+	"context"
+
+	"github.com/tychoish/grip/message"
+)
+
+// ContextSender is implemented by senders that can make use of the
+// context of a logging call: to read values from the context (e.g.
+// for trace correlation) or to respect the context's deadline when
+// delivering messages over the network. Senders decide how to handle
+// canceled contexts; the implementations in this package deliver
+// messages regardless, except for the async group sender (see
+// MakeAsyncGroup), which drops messages rather than wait for buffer
+// space after the context ends.
+type ContextSender interface {
+	Sender
+	SendContext(context.Context, message.Composer)
+}
+
+// SendContext delivers the message to the sender using its
+// SendContext method if the sender implements ContextSender, and its
+// Send method otherwise.
+func SendContext(ctx context.Context, s Sender, m message.Composer) {
+	if cs, ok := s.(ContextSender); ok {
+		cs.SendContext(ctx, m)
+		return
+	}
+	s.Send(m)
+}
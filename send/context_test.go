@@ -0,0 +1,65 @@
+package send
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+type ctxTestKey struct{}
+
+type ctxRecordingSender struct {
+	Base
+	values []any
+	sends  int
+}
+
+func (s *ctxRecordingSender) Send(message.Composer) { s.sends++ }
+func (s *ctxRecordingSender) SendContext(ctx context.Context, m message.Composer) {
+	if ShouldLog(s, m) {
+		s.values = append(s.values, ctx.Value(ctxTestKey{}))
+	}
+}
+
+func TestSendContext(t *testing.T) {
+	ctx := context.WithValue(t.Context(), ctxTestKey{}, "value")
+
+	t.Run("Fallback", func(t *testing.T) {
+		internal := MakeInternal()
+		SendContext(ctx, internal, NewSimpleString(level.Info, "hi"))
+		check.Equal(t, internal.Len(), 1)
+	})
+	t.Run("ContextSender", func(t *testing.T) {
+		rec := &ctxRecordingSender{}
+		SendContext(ctx, rec, NewSimpleString(level.Info, "hi"))
+		check.Equal(t, rec.sends, 0)
+		check.Equal(t, len(rec.values), 1)
+		check.Equal(t, rec.values[0], any("value"))
+	})
+	t.Run("Wrappers", func(t *testing.T) {
+		var filtered int
+		for name, wrap := range map[string]func(Sender) Sender{
+			"Annotating": func(s Sender) Sender { return MakeAnnotating(s, map[string]any{"a": 1}) },
+			"Filter":     func(s Sender) Sender { return MakeFilter(s, func(message.Composer) { filtered++ }) },
+			"Multi":      func(s Sender) Sender { return MakeMulti(s) },
+			"Options":    func(s Sender) Sender { return WithOptionSender(s, message.OptionCollectInfo) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				rec := &ctxRecordingSender{}
+				wrapped := wrap(rec)
+				wrapped.SetPriority(level.Info)
+				rec.SetPriority(level.Info)
+
+				SendContext(ctx, wrapped, NewSimpleString(level.Info, "hi"))
+				SendContext(ctx, wrapped, NewSimpleString(level.Debug, "quiet"))
+				check.Equal(t, rec.sends, 0)
+				check.Equal(t, len(rec.values), 1)
+				check.Equal(t, rec.values[0], any("value"))
+			})
+		}
+		check.Equal(t, filtered, 2)
+	})
+}
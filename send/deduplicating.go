@@ -0,0 +1,217 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+type deduplicatingSender struct {
+	Sender
+	window time.Duration
+	key    func(message.Composer) string
+	now    func() time.Time
+	cancel context.CancelFunc
+	done   chan struct{}
+	close  sync.Once
+
+	mu     sync.Mutex
+	seen   map[uint64]*repeatState
+	closed bool
+}
+
+type repeatState struct {
+	start    time.Time
+	rendered string
+	priority level.Priority
+	count    int
+}
+
+// MakeDeduplicating wraps a sender so that identical messages, by
+// their rendered (string) form, are only sent once during the
+// window. When the window closes, or when the sender is flushed or
+// closed, a follow-up message reports the number of times that the
+// suppressed message repeated. If the window is less than or equal
+// to zero, it defaults to one minute.
+//
+// The deduplicating sender owns the underlying sender: closing the
+// deduplicating sender sends the remaining follow-up messages and
+// closes the underlying sender.
+func MakeDeduplicating(sender Sender, window time.Duration) Sender {
+	return MakeDeduplicatingBy(sender, window, func(m message.Composer) string { return m.String() })
+}
+
+// MakeDeduplicatingBy is the same as MakeDeduplicating, but
+// identifies repeated messages using the key function. For example,
+// to compare structured messages by their payload:
+//
+//	send.MakeDeduplicatingBy(sender, time.Minute, func(m message.Composer) string {
+//		return fmt.Sprint(m.Raw())
+//	})
+func MakeDeduplicatingBy(sender Sender, window time.Duration, key func(message.Composer) string) Sender {
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &deduplicatingSender{
+		Sender: sender,
+		window: window,
+		key:    key,
+		now:    time.Now,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		seen:   map[uint64]*repeatState{},
+	}
+
+	go s.expireLoop(ctx)
+
+	return s
+}
+
+func (s *deduplicatingSender) Unwrap() Sender { return s.Sender }
+
+func (s *deduplicatingSender) Send(m message.Composer) {
+	ok, prev := s.admit(m)
+	if prev != nil {
+		s.Sender.Send(prev)
+	}
+	if ok {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *deduplicatingSender) SendContext(ctx context.Context, m message.Composer) {
+	ok, prev := s.admit(m)
+	if prev != nil {
+		SendContext(ctx, s.Sender, prev)
+	}
+	if ok {
+		SendContext(ctx, s.Sender, m)
+	}
+}
+
+// admit reports if the message should be sent, and returns the
+// follow-up for the previous window of the same message if it has
+// expired, which the caller must send first. Messages are sent
+// without holding the lock, so that slow senders do not block other
+// callers.
+func (s *deduplicatingSender) admit(m message.Composer) (bool, message.Composer) {
+	if !ShouldLog(s, m) {
+		return false, nil
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(s.key(m)))
+	key := hash.Sum64()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return true, nil
+	}
+
+	var prev message.Composer
+	now := s.now()
+	if st, ok := s.seen[key]; ok {
+		if now.Sub(st.start) < s.window {
+			st.count++
+			return false, nil
+		}
+		prev = st.followUp()
+	}
+
+	s.seen[key] = &repeatState{start: now, rendered: m.String(), priority: m.Priority()}
+	return true, prev
+}
+
+// followUp returns the follow-up message for the state, or nil if
+// the message did not repeat, and resets its count. followUp must be
+// called with the lock held.
+func (st *repeatState) followUp() message.Composer {
+	if st.count == 0 {
+		return nil
+	}
+
+	m := message.NewKV().
+		KV(message.FieldsMsgName, fmt.Sprintf("last message repeated %d times", st.count)).
+		KV("repeated", st.count).
+		KV("original", st.rendered)
+	m.SetPriority(st.priority)
+	st.count = 0
+	return m
+}
+
+// expire sends the follow-up messages for the windows that have
+// closed (or for all windows, if force is set,) and forgets the
+// messages whose windows have closed.
+func (s *deduplicatingSender) expire(force bool) {
+	for _, m := range s.expired(force) {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *deduplicatingSender) expired(force bool) []message.Composer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []message.Composer
+	now := s.now()
+	for key, st := range s.seen {
+		expired := now.Sub(st.start) >= s.window
+		if expired || force {
+			if m := st.followUp(); m != nil {
+				out = append(out, m)
+			}
+		}
+		if expired {
+			delete(s.seen, key)
+		}
+	}
+	return out
+}
+
+func (s *deduplicatingSender) expireLoop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expire(false)
+		}
+	}
+}
+
+// Flush sends the follow-up messages for all suppressed messages,
+// and then flushes the underlying sender. Repeats of these messages
+// continue to be suppressed until their windows close.
+func (s *deduplicatingSender) Flush(ctx context.Context) error {
+	s.expire(true)
+	return s.Sender.Flush(ctx)
+}
+
+func (s *deduplicatingSender) Close() error {
+	s.close.Do(func() {
+		s.cancel()
+		<-s.done
+		s.expire(true)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		clear(s.seen)
+	})
+
+	return s.Sender.Close()
+}
@@ -0,0 +1,140 @@
+package send
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func newTestDeduplicating(t *testing.T, key func(message.Composer) string) (*deduplicatingSender, *InternalSender, *testClock) {
+	t.Helper()
+	internal := newTestInternal()
+	var s Sender
+	if key == nil {
+		s = MakeDeduplicating(internal, time.Hour)
+	} else {
+		s = MakeDeduplicatingBy(internal, time.Hour, key)
+	}
+	dedup := s.(*deduplicatingSender)
+	return dedup, internal, withTestClock(&dedup.now)
+}
+
+func TestDeduplicatingSender(t *testing.T) {
+	t.Run("SuppressesRepeats", func(t *testing.T) {
+		s, internal, clock := newTestDeduplicating(t, nil)
+		for range 5 {
+			s.Send(NewString(level.Error, "connection refused"))
+			s.Send(NewString(level.Info, "other"))
+		}
+		s.Send(NewString(level.Debug, "below threshold"))
+		check.Equal(t, internal.Len(), 2)
+		internal.GetMessage()
+		internal.GetMessage()
+
+		// the next message after the window closes sends the
+		// follow-up first
+		clock.Advance(time.Hour)
+		s.Send(NewString(level.Error, "connection refused"))
+		check.Equal(t, internal.Len(), 2)
+
+		followUp := internal.GetMessage()
+		check.Equal(t, followUp.Priority, level.Error)
+		check.Substring(t, followUp.Rendered, "msg='last message repeated 4 times'")
+		check.Substring(t, followUp.Rendered, "repeated='4'")
+		check.Substring(t, followUp.Rendered, "original='connection refused'")
+		check.Equal(t, internal.GetMessage().Rendered, "connection refused")
+	})
+	t.Run("Expire", func(t *testing.T) {
+		s, internal, clock := newTestDeduplicating(t, nil)
+		s.Send(NewString(level.Info, "hello"))
+		s.Send(NewString(level.Info, "hello"))
+		s.Send(NewString(level.Info, "once"))
+		internal.GetMessage()
+		internal.GetMessage()
+
+		s.expire(false)
+		check.Equal(t, internal.Len(), 0)
+
+		clock.Advance(time.Hour)
+		s.expire(false)
+		check.Equal(t, internal.Len(), 1)
+		check.Substring(t, internal.GetMessage().Rendered, "repeated 1 times")
+		check.Equal(t, len(s.seen), 0)
+	})
+	t.Run("Reentrant", func(t *testing.T) {
+		internal := newTestInternal()
+
+		// the downstream sender logs back through the
+		// deduplicating sender when it sees a follow-up, which
+		// deadlocks if the follow-up is sent with the lock held.
+		var dedup Sender
+		dedup = MakeDeduplicating(MakeFilter(internal, func(m message.Composer) {
+			if kv, ok := m.(*message.KV); ok && kv.Loggable() {
+				dedup.Send(NewString(level.Info, "from downstream"))
+			}
+		}), time.Hour)
+		dedup.SetPriority(level.Info)
+
+		dedup.Send(NewString(level.Info, "hello"))
+		dedup.Send(NewString(level.Info, "hello"))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			check.NotError(t, dedup.Flush(t.Context()))
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("sending the follow-up deadlocked")
+		}
+		check.Equal(t, internal.Len(), 3)
+	})
+	t.Run("Flush", func(t *testing.T) {
+		s, internal, _ := newTestDeduplicating(t, nil)
+		for range 3 {
+			s.Send(NewString(level.Info, "hello"))
+		}
+		check.NotError(t, s.Flush(t.Context()))
+		check.Equal(t, internal.Len(), 2)
+		internal.GetMessage()
+		check.Substring(t, internal.GetMessage().Rendered, "repeated 2 times")
+
+		// still inside the window
+		s.Send(NewString(level.Info, "hello"))
+		check.Equal(t, internal.Len(), 0)
+	})
+	t.Run("Close", func(t *testing.T) {
+		s, internal, _ := newTestDeduplicating(t, nil)
+		s.Send(NewString(level.Info, "hello"))
+		SendContext(t.Context(), s, NewString(level.Info, "hello"))
+		check.NotError(t, s.Close())
+		check.Equal(t, internal.Len(), 2)
+		internal.GetMessage()
+		check.Substring(t, internal.GetMessage().Rendered, "repeated 1 times")
+	})
+	t.Run("KeyFunction", func(t *testing.T) {
+		s, internal, _ := newTestDeduplicating(t, func(m message.Composer) string {
+			return fmt.Sprint(m.Priority())
+		})
+		s.Send(NewString(level.Info, "one"))
+		s.Send(NewString(level.Info, "two"))
+		s.Send(NewString(level.Error, "three"))
+		check.Equal(t, internal.Len(), 2)
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"deduplicating","priority":"info","options":{"window":"1h"},"children":[{"type":"inmemory"}]}`))
+		check.NotError(t, err)
+		for range 5 {
+			sender.Send(NewString(level.Info, "hello"))
+		}
+		mem := sender.(*deduplicatingSender).Sender.(*InMemorySender)
+		check.Equal(t, len(mem.Get()), 1)
+		check.NotError(t, sender.Close())
+		check.Equal(t, len(mem.Get()), 2)
+	})
+}
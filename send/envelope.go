@@ -0,0 +1,435 @@
+package send
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// EnvelopeOptions configure envelope formatters. The Key fields name
+// the members of the envelope; members with empty keys are omitted.
+// Use the preset functions (e.g. DefaultEnvelopeOptions and
+// ECSEnvelopeOptions) as starting points.
+type EnvelopeOptions struct {
+	TimeKey    string
+	LevelKey   string
+	MessageKey string
+	FieldsKey  string
+	ErrorKey   string
+	StackKey   string
+	CallerKey  string
+	HostKey    string
+	PidKey     string
+
+	// InlineFields renders the message's fields as members of the
+	// envelope, rather than as a document in the FieldsKey
+	// member. Fields with the same name as a member of the
+	// envelope are prefixed with an underscore.
+	InlineFields bool
+	// TimeLayout is the layout of the time member, which holds the
+	// message's timestamp (see message.Timestamp), and defaults to
+	// time.RFC3339Nano.
+	TimeLayout string
+	// CallerDepth, when positive, records the call site with the
+	// same meaning as the argument to MakeCallSiteFormatter. The
+	// caller of stack messages is always the first frame of the
+	// stack.
+	CallerDepth int
+
+	// Level renders the priority of the message, and defaults to
+	// the name of the priority.
+	Level func(level.Priority) string
+	// Caller renders the call site, and defaults to "file:line".
+	Caller func(message.StackFrame) any
+	// Stack renders the frames of stack messages, and defaults to
+	// a list of frame documents.
+	Stack func(message.StackFrames) any
+
+	timestamp func(message.Composer) time.Time
+}
+
+// DefaultEnvelopeOptions returns the options for envelopes, as in:
+//
+//	{"ts":"...","level":"error","msg":"...","error":"...","stack":[...],"caller":"...","host":"...","pid":1,"fields":{...}}
+func DefaultEnvelopeOptions() EnvelopeOptions {
+	return EnvelopeOptions{
+		TimeKey:    "ts",
+		LevelKey:   "level",
+		MessageKey: "msg",
+		FieldsKey:  "fields",
+		ErrorKey:   "error",
+		StackKey:   "stack",
+		CallerKey:  "caller",
+		HostKey:    "host",
+		PidKey:     "pid",
+	}
+}
+
+// ECSEnvelopeOptions returns the options for envelopes that follow
+// the Elastic Common Schema. Fields are inlined.
+func ECSEnvelopeOptions() EnvelopeOptions {
+	return EnvelopeOptions{
+		TimeKey:      "@timestamp",
+		LevelKey:     "log.level",
+		MessageKey:   "message",
+		ErrorKey:     "error.message",
+		StackKey:     "error.stack_trace",
+		CallerKey:    "log.origin",
+		HostKey:      "host.hostname",
+		PidKey:       "process.pid",
+		InlineFields: true,
+		Caller: func(frame message.StackFrame) any {
+			return map[string]any{
+				"file":     map[string]any{"name": frame.File, "line": frame.Line},
+				"function": frame.Function,
+			}
+		},
+		Stack: stackString,
+	}
+}
+
+// GCPEnvelopeOptions returns the options for envelopes that follow
+// the structured logging format of Google Cloud Logging, which
+// records severities rather than priorities (e.g. "WARNING".) Fields
+// are inlined, and the host and process id are omitted.
+func GCPEnvelopeOptions() EnvelopeOptions {
+	return EnvelopeOptions{
+		TimeKey:      "timestamp",
+		LevelKey:     "severity",
+		MessageKey:   "message",
+		ErrorKey:     "error",
+		StackKey:     "stack_trace",
+		CallerKey:    "logging.googleapis.com/sourceLocation",
+		InlineFields: true,
+		Level:        gcpSeverity,
+		Caller: func(frame message.StackFrame) any {
+			return map[string]any{"file": frame.File, "line": frame.Line, "function": frame.Function}
+		},
+		Stack: stackString,
+	}
+}
+
+// DatadogEnvelopeOptions returns the options for envelopes that use
+// the reserved attributes of Datadog's log management. Fields are
+// inlined.
+func DatadogEnvelopeOptions() EnvelopeOptions {
+	return EnvelopeOptions{
+		TimeKey:      "timestamp",
+		LevelKey:     "status",
+		MessageKey:   "message",
+		ErrorKey:     "error.message",
+		StackKey:     "error.stack",
+		CallerKey:    "logger.caller",
+		HostKey:      "host",
+		PidKey:       "pid",
+		InlineFields: true,
+		Stack:        stackString,
+	}
+}
+
+func gcpSeverity(p level.Priority) string {
+	switch {
+	case p >= level.Emergency:
+		return "EMERGENCY"
+	case p >= level.Alert:
+		return "ALERT"
+	case p >= level.Critical:
+		return "CRITICAL"
+	case p >= level.Error:
+		return "ERROR"
+	case p >= level.Warning:
+		return "WARNING"
+	case p >= level.Notice:
+		return "NOTICE"
+	case p >= level.Info:
+		return "INFO"
+	case p > level.Invalid:
+		return "DEBUG"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// stackString renders the frames as lines, as in:
+//
+//	send/envelope.go:42 (MakeEnvelopeFormatter)
+func stackString(frames message.StackFrames) any { return strings.Join(stackLines(frames), "\n") }
+
+func stackLines(frames message.StackFrames) []string {
+	out := make([]string, len(frames))
+	for idx, frame := range frames {
+		out[idx] = fmt.Sprintf("%s:%d (%s)", frame.File, frame.Line, frame.Function)
+	}
+	return out
+}
+
+// MakeEnvelopeFormatter returns a MessageFormatter that renders
+// every message as a JSON document with the same shape, regardless
+// of the type of the message: the time, priority, message, error,
+// stack, call site, host, and process id are members of the
+// envelope, and the message's other fields are rendered in order,
+// either within the fields member or inlined.
+//
+// The error member holds the text of error messages (see
+// message.MakeError) and of "error" fields (as in
+// message.WrapError.) The stack member holds the frames of stack
+// messages (see message.MakeStack.) Group messages produce one
+// envelope, on its own line, for each message in the group.
+//
+// The formatter returns an error if the message's fields cannot be
+// rendered as JSON.
+func MakeEnvelopeFormatter(opts EnvelopeOptions) MessageFormatter {
+	if opts.TimeLayout == "" {
+		opts.TimeLayout = time.RFC3339Nano
+	}
+	if opts.Level == nil {
+		opts.Level = level.Priority.String
+	}
+	if opts.Caller == nil {
+		opts.Caller = func(frame message.StackFrame) any { return fmt.Sprintf("%s:%d", frame.File, frame.Line) }
+	}
+	if opts.Stack == nil {
+		opts.Stack = func(frames message.StackFrames) any { return frames }
+	}
+	if opts.timestamp == nil {
+		opts.timestamp = message.Timestamp
+	}
+
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+
+	var format MessageFormatter
+	format = func(m message.Composer) (string, error) {
+		if group, ok := m.(*message.GroupComposer); ok {
+			var lines []string
+			for _, msg := range group.Messages() {
+				if !msg.Loggable() {
+					continue
+				}
+				line, err := format(msg)
+				if err != nil {
+					return "", err
+				}
+				lines = append(lines, line)
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+
+		content := envelopeContent(m)
+		if len(content.frames) > 0 {
+			content.caller = &content.frames[0]
+		} else if opts.CallerDepth > 0 {
+			// as in MakeCallSiteFormatter, the depth is
+			// relative to the caller of the formatter.
+			if pc, file, line, ok := runtime.Caller(opts.CallerDepth + 1); ok {
+				content.caller = &message.StackFrame{Function: runtime.FuncForPC(pc).Name(), File: file, Line: line}
+			}
+		}
+
+		var doc envelopeWriter
+		doc.member(opts.TimeKey, opts.timestamp(m).Format(opts.TimeLayout))
+		doc.member(opts.LevelKey, opts.Level(m.Priority()))
+		doc.member(opts.MessageKey, content.msg)
+		if content.err != "" {
+			doc.member(opts.ErrorKey, content.err)
+		}
+		if len(content.frames) > 0 {
+			doc.member(opts.StackKey, opts.Stack(shortStack(content.frames)))
+		}
+		if content.caller != nil {
+			doc.member(opts.CallerKey, opts.Caller(shortStack(message.StackFrames{*content.caller})[0]))
+		}
+		doc.member(opts.HostKey, host)
+		doc.member(opts.PidKey, pid)
+
+		switch {
+		case opts.InlineFields:
+			reserved := []string{
+				opts.TimeKey, opts.LevelKey, opts.MessageKey, opts.ErrorKey,
+				opts.StackKey, opts.CallerKey, opts.HostKey, opts.PidKey,
+			}
+			for _, field := range content.fields {
+				key := field.key
+				for slices.Contains(reserved, key) {
+					key = "_" + key
+				}
+				doc.member(key, field.value)
+			}
+		case len(content.fields) > 0:
+			var fields envelopeWriter
+			for _, field := range content.fields {
+				fields.member(field.key, field.value)
+			}
+			if fields.err == nil {
+				doc.member(opts.FieldsKey, json.RawMessage(fields.close()))
+			} else {
+				doc.err = fields.err
+			}
+		}
+
+		if doc.err != nil {
+			return "", doc.err
+		}
+		return doc.close(), nil
+	}
+	return format
+}
+
+// envelopeWriter renders JSON documents with members in the order
+// they're added.
+type envelopeWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (w *envelopeWriter) member(key string, value any) {
+	if key == "" || w.err != nil {
+		return
+	}
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	out, err := json.Marshal(value)
+	if err != nil {
+		w.err = fmt.Errorf("rendering %q: %w", key, err)
+		return
+	}
+
+	if w.buf.Len() == 0 {
+		w.buf.WriteByte('{')
+	} else {
+		w.buf.WriteByte(',')
+	}
+	name, _ := json.Marshal(key)
+	w.buf.Write(name)
+	w.buf.WriteByte(':')
+	w.buf.Write(out)
+}
+
+func (w *envelopeWriter) close() string {
+	if w.buf.Len() == 0 {
+		return "{}"
+	}
+	w.buf.WriteByte('}')
+	return w.buf.String()
+}
+
+type envelope struct {
+	msg    string
+	err    string
+	frames message.StackFrames
+	caller *message.StackFrame
+	fields []fieldPair
+}
+
+// envelopeContent collects the members of the envelope from the
+// message.
+func envelopeContent(m message.Composer) *envelope {
+	out := &envelope{}
+	raw := m.Raw()
+
+	if trace, ok := raw.(message.StackTrace); ok {
+		// the string form of stack messages begins with the
+		// frames, and the message's document is the context.
+		out.frames = trace.Frames
+		out.msg = strings.TrimSpace(strings.TrimPrefix(m.String(), trace.Frames.String()))
+		raw = trace.Context
+	}
+
+	hasDocument := true
+	switch doc := raw.(type) {
+	case interface{ Iterator() iter.Seq2[string, any] }:
+		for key, value := range doc.Iterator() {
+			out.add(key, value)
+		}
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(doc)) {
+			out.add(key, doc[key])
+		}
+	default:
+		if raw == nil || !m.Structured() {
+			hasDocument = false
+			break
+		}
+		// the document of other messages is either a struct,
+		// (converted using the json tags of its fields,) or
+		// whatever JSON makes of the value.
+		if kv, ok := message.ConvertStruct(raw); ok {
+			for key, value := range kv.Raw().(interface{ Iterator() iter.Seq2[string, any] }).Iterator() {
+				out.add(key, value)
+			}
+			break
+		}
+		var fields map[string]any
+		if data, err := json.Marshal(raw); err == nil && json.Unmarshal(data, &fields) == nil {
+			for _, key := range slices.Sorted(maps.Keys(fields)) {
+				out.add(key, fields[key])
+			}
+		} else {
+			hasDocument = false
+		}
+	}
+
+	if _, isErr := m.(error); isErr && out.err == "" {
+		out.err = m.String()
+	}
+	if out.msg == "" && (!hasDocument || out.err != "") {
+		out.msg = m.String()
+	}
+	return out
+}
+
+func (e *envelope) add(key string, value any) {
+	switch val := value.(type) {
+	case message.StackFrames:
+		e.frames = val
+		return
+	case error:
+		if key == "error" && e.err == "" {
+			e.err = val.Error()
+			return
+		}
+	case map[string]any:
+		// the annotations of string messages
+		if key == "context" {
+			for _, k := range slices.Sorted(maps.Keys(val)) {
+				e.add(k, val[k])
+			}
+			return
+		}
+	}
+
+	switch key {
+	case message.FieldsMsgName:
+		if e.msg == "" {
+			e.msg = fmt.Sprint(value)
+		}
+	case "meta":
+	default:
+		e.fields = append(e.fields, fieldPair{key: key, value: value})
+	}
+}
+
+// shortStack trims the file names of the frames to the name of the
+// file and its directory, as with the call site formatter.
+func shortStack(frames message.StackFrames) message.StackFrames {
+	out := make(message.StackFrames, len(frames))
+	for idx, frame := range frames {
+		dir, file := filepath.Split(frame.File)
+		frame.File = filepath.Join(filepath.Base(dir), file)
+		out[idx] = frame
+	}
+	return out
+}
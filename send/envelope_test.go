@@ -0,0 +1,173 @@
+package send
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestEnvelopeFormatter(t *testing.T) {
+	clock := func() time.Time { return time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC) }
+	host, _ := os.Hostname()
+
+	render := func(t *testing.T, opts EnvelopeOptions, m message.Composer) map[string]any {
+		t.Helper()
+		opts.timestamp = func(message.Composer) time.Time { return clock() }
+		out, err := MakeEnvelopeFormatter(opts)(m)
+		check.NotError(t, err)
+		doc := map[string]any{}
+		check.NotError(t, json.Unmarshal([]byte(out), &doc))
+		return doc
+	}
+
+	t.Run("Shapes", func(t *testing.T) {
+		kv := message.NewKV().KV("msg", "request complete").KV("status", 200)
+		fields := message.MakeFields(message.Fields{"msg": "request complete", "status": 200})
+		str := message.MakeString("request complete")
+		str.Annotate("status", 200)
+		for name, m := range map[string]message.Composer{"KV": kv, "Fields": fields, "String": str} {
+			t.Run(name, func(t *testing.T) {
+				m.SetPriority(level.Info)
+				doc := render(t, DefaultEnvelopeOptions(), m)
+				check.Equal(t, doc["ts"], "2024-01-02T15:04:05Z")
+				check.Equal(t, doc["level"], "info")
+				check.Equal(t, doc["msg"], "request complete")
+				check.Equal(t, doc["host"], any(host))
+				check.Equal(t, doc["pid"], any(float64(os.Getpid())))
+				check.Equal(t, len(doc["fields"].(map[string]any)), 1)
+				check.Equal(t, doc["fields"].(map[string]any)["status"], any(float64(200)))
+				_, ok := doc["error"]
+				check.True(t, !ok)
+			})
+		}
+	})
+	t.Run("Order", func(t *testing.T) {
+		opts := DefaultEnvelopeOptions()
+		opts.timestamp = func(message.Composer) time.Time { return clock() }
+		opts.HostKey, opts.PidKey = "", ""
+		m := message.NewKV().KV("msg", "hi").KV("b", 1).KV("a", 2)
+		m.SetPriority(level.Debug)
+		out, err := MakeEnvelopeFormatter(opts)(m)
+		check.NotError(t, err)
+		check.Equal(t, out, `{"ts":"2024-01-02T15:04:05Z","level":"debug","msg":"hi","fields":{"b":1,"a":2}}`)
+	})
+	t.Run("Timestamp", func(t *testing.T) {
+		m := message.MakeString("collected")
+		m.SetPriority(level.Info)
+		m.SetOption(message.OptionCollectInfo)
+		out, err := MakeEnvelopeFormatter(DefaultEnvelopeOptions())(m)
+		check.NotError(t, err)
+		doc := map[string]any{}
+		check.NotError(t, json.Unmarshal([]byte(out), &doc))
+		check.Equal(t, doc["ts"], any(message.Timestamp(m).Format(time.RFC3339Nano)))
+	})
+	t.Run("Errors", func(t *testing.T) {
+		m := message.MakeError(errors.New("connection refused"))
+		m.SetPriority(level.Error)
+		doc := render(t, DefaultEnvelopeOptions(), m)
+		check.Equal(t, doc["msg"], "connection refused")
+		check.Equal(t, doc["error"], "connection refused")
+
+		wrapped := message.WrapError(errors.New("timeout"), message.NewKV().KV("msg", "query failed").KV("db", "users"))
+		wrapped.SetPriority(level.Error)
+		doc = render(t, DefaultEnvelopeOptions(), wrapped)
+		check.Equal(t, doc["msg"], "query failed")
+		check.Equal(t, doc["error"], "timeout")
+		check.Equal(t, doc["fields"].(map[string]any)["db"], "users")
+
+		wrapped = message.WrapError(errors.New("timeout"), "query failed")
+		wrapped.SetPriority(level.Error)
+		doc = render(t, DefaultEnvelopeOptions(), wrapped)
+		check.Equal(t, doc["error"], "timeout")
+		_, ok := doc["fields"]
+		check.True(t, !ok)
+	})
+	t.Run("Stack", func(t *testing.T) {
+		m := message.MakeStack(1, "captured")
+		m.SetPriority(level.Warning)
+		doc := render(t, DefaultEnvelopeOptions(), m)
+		check.Equal(t, doc["msg"], "captured")
+		check.True(t, strings.HasPrefix(doc["caller"].(string), "send/envelope_test.go:"))
+		frames := doc["stack"].([]any)
+		check.True(t, len(frames) > 1)
+		check.Equal(t, frames[0].(map[string]any)["file"], "send/envelope_test.go")
+
+		structured := message.WrapStack(1, message.NewKV().KV("msg", "structured").KV("id", 42))
+		structured.SetPriority(level.Warning)
+		doc = render(t, ECSEnvelopeOptions(), structured)
+		check.Equal(t, doc["message"], "structured")
+		check.Equal(t, doc["id"], any(float64(42)))
+		check.True(t, strings.HasPrefix(doc["error.stack_trace"].(string), "send/envelope_test.go:"))
+		_, ok := doc["stack.frames"]
+		check.True(t, !ok)
+	})
+	t.Run("Caller", func(t *testing.T) {
+		opts := DefaultEnvelopeOptions()
+		opts.CallerDepth = 1
+		check.True(t, strings.HasPrefix(render(t, opts, NewString(level.Info, "hi"))["caller"].(string), "send/envelope_test.go:"))
+		_, ok := render(t, DefaultEnvelopeOptions(), NewString(level.Info, "hi"))["caller"]
+		check.True(t, !ok)
+	})
+	t.Run("Group", func(t *testing.T) {
+		opts := DefaultEnvelopeOptions()
+		opts.timestamp = func(message.Composer) time.Time { return clock() }
+		out, err := MakeEnvelopeFormatter(opts)(message.BuildGroupComposer(
+			NewString(level.Info, "one"), NewString(level.Info, "two")))
+		check.NotError(t, err)
+		lines := strings.Split(out, "\n")
+		check.Equal(t, len(lines), 2)
+		check.Substring(t, lines[0], `"msg":"one"`)
+		check.Substring(t, lines[1], `"msg":"two"`)
+	})
+	t.Run("Presets", func(t *testing.T) {
+		m := message.NewKV().KV("msg", "disk full").KV("message", "collides").KV("path", "/var")
+		m.SetPriority(level.Warning)
+
+		doc := render(t, ECSEnvelopeOptions(), m)
+		check.Equal(t, doc["@timestamp"], "2024-01-02T15:04:05Z")
+		check.Equal(t, doc["log.level"], "warning")
+		check.Equal(t, doc["message"], "disk full")
+		check.Equal(t, doc["_message"], "collides")
+		check.Equal(t, doc["path"], "/var")
+		check.Equal(t, doc["host.hostname"], any(host))
+
+		doc = render(t, GCPEnvelopeOptions(), m)
+		check.Equal(t, doc["severity"], "WARNING")
+		check.Equal(t, doc["message"], "disk full")
+		_, ok := doc["host"]
+		check.True(t, !ok)
+
+		doc = render(t, DatadogEnvelopeOptions(), m)
+		check.Equal(t, doc["status"], "warning")
+		check.Equal(t, doc["host"], any(host))
+
+		for p, severity := range map[level.Priority]string{
+			level.Trace: "DEBUG", level.Info: "INFO", level.Notice: "NOTICE",
+			level.Critical: "CRITICAL", level.Emergency: "EMERGENCY", level.Invalid: "DEFAULT",
+		} {
+			check.Equal(t, gcpSeverity(p), severity)
+		}
+	})
+	t.Run("MarshalError", func(t *testing.T) {
+		m := message.NewKV().KV("msg", "bad").KV("ch", make(chan int))
+		m.SetPriority(level.Info)
+		_, err := MakeEnvelopeFormatter(DefaultEnvelopeOptions())(m)
+		check.Error(t, err)
+	})
+	t.Run("Spec", func(t *testing.T) {
+		for _, name := range []string{"envelope", "ecs", "gcp", "datadog"} {
+			s, err := BuildJSON([]byte(`{"type":"nop","formatter":"` + name + `"}`))
+			check.NotError(t, err)
+			out, err := s.GetFormatter()(NewString(level.Info, "hi"))
+			check.NotError(t, err)
+			check.True(t, json.Valid([]byte(out)))
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// EscalationOptions configures an escalating sender (see
+// MakeEscalating.) The zero value uses the defaults for all options.
+type EscalationOptions struct {
+	// Threshold is the number of messages at or above the Trigger
+	// priority, within the Window, that starts an
+	// escalation. Defaults to 10.
+	Threshold int
+	// Window is the period over which the messages are
+	// counted. Defaults to one minute.
+	Window time.Duration
+	// Duration is how long the escalation lasts. When the
+	// threshold is exceeded again during an escalation, the
+	// escalation is extended. Defaults to five minutes.
+	Duration time.Duration
+	// Trigger is the lowest priority that counts towards the
+	// threshold. Defaults to level.Error.
+	Trigger level.Priority
+	// Level is the priority of the sender during an
+	// escalation. Defaults to level.Debug.
+	Level level.Priority
+}
+
+// EscalationState reports the state of an escalating sender.
+type EscalationState struct {
+	// Escalated is true during an escalation.
+	Escalated bool
+	// Since and Until are the start and the (current) end of the
+	// escalation, and are zero when the sender is not escalated.
+	Since time.Time
+	Until time.Time
+	// Recent is the number of messages at or above the trigger
+	// priority in the current window.
+	Recent int
+	// Priority is the sender's priority outside of escalations.
+	Priority level.Priority
+}
+
+// EscalatingSender is implemented by the senders that MakeEscalating
+// produces.
+type EscalatingSender interface {
+	Sender
+	EscalationState() EscalationState
+}
+
+type escalatingSender struct {
+	Sender
+	opts EscalationOptions
+	now  func() time.Time
+
+	mu        sync.Mutex
+	recent    []time.Time
+	escalated bool
+	restore   level.Priority
+	since     time.Time
+	until     time.Time
+	timer     *time.Timer
+	closed    bool
+}
+
+// MakeEscalating wraps a sender so that, when the rate of errors
+// spikes, the sender temporarily lowers its priority (to Debug, by
+// default) to capture more context, and restores the previous
+// priority after the configured duration. The sender sends a Notice
+// message when the escalation begins and when it ends.
+//
+// Setting the priority of the sender during an escalation changes the
+// priority that the sender restores when the escalation ends.
+//
+// To buffer the additional messages, wrap a buffered sender:
+//
+//	send.MakeEscalating(send.MakeBuffered(sender, time.Minute, 1000), send.EscalationOptions{})
+//
+// The escalating sender owns the underlying sender, and closing the
+// escalating sender closes the underlying sender.
+func MakeEscalating(sender Sender, opts EscalationOptions) EscalatingSender {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 10
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = 5 * time.Minute
+	}
+	if opts.Trigger == level.Invalid {
+		opts.Trigger = level.Error
+	}
+	if opts.Level == level.Invalid {
+		opts.Level = level.Debug
+	}
+
+	return &escalatingSender{
+		Sender: sender,
+		opts:   opts,
+		now:    time.Now,
+		recent: make([]time.Time, 0, opts.Threshold),
+	}
+}
+
+func (s *escalatingSender) Unwrap() Sender { return s.Sender }
+
+func (s *escalatingSender) Send(m message.Composer) {
+	s.observe(m)
+	if ShouldLog(s, m) {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *escalatingSender) SendContext(ctx context.Context, m message.Composer) {
+	s.observe(m)
+	if ShouldLog(s, m) {
+		SendContext(ctx, s.Sender, m)
+	}
+}
+
+func (s *escalatingSender) SetPriority(p level.Priority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.escalated {
+		s.restore = p
+		return
+	}
+	s.Sender.SetPriority(p)
+}
+
+func (s *escalatingSender) EscalationState() EscalationState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	s.trim(now)
+
+	state := EscalationState{Escalated: s.escalated, Recent: len(s.recent), Priority: s.Sender.Priority()}
+	if s.escalated {
+		state.Since, state.Until, state.Priority = s.since, s.until, s.restore
+	}
+	return state
+}
+
+// observe counts the message, if its priority is at or above the
+// trigger, and starts or extends the escalation when the count
+// reaches the threshold.
+func (s *escalatingSender) observe(m message.Composer) {
+	counts := m != nil && m.Priority() >= s.opts.Trigger && m.Loggable()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if !counts || s.closed {
+		return
+	}
+
+	s.trim(now)
+	if len(s.recent) == s.opts.Threshold {
+		s.recent = append(s.recent[:0], s.recent[1:]...)
+	}
+	s.recent = append(s.recent, now)
+	if len(s.recent) < s.opts.Threshold {
+		return
+	}
+
+	switch {
+	case s.escalated:
+		s.until = now.Add(s.opts.Duration)
+		s.timer.Reset(s.opts.Duration)
+	case s.Sender.Priority() > s.opts.Level:
+		s.escalated = true
+		s.restore = s.Sender.Priority()
+		s.since = now
+		s.until = now.Add(s.opts.Duration)
+		s.Sender.SetPriority(s.opts.Level)
+		s.notify(fmt.Sprintf("escalated logging to %s after %d %s messages in %s",
+			s.opts.Level, len(s.recent), s.opts.Trigger, s.opts.Window), true)
+		s.timer = time.AfterFunc(s.opts.Duration, s.timeout)
+	}
+}
+
+// trim forgets the messages that are outside of the window, and must
+// be called with the lock held.
+func (s *escalatingSender) trim(now time.Time) {
+	cutoff := now.Add(-s.opts.Window)
+	idx := 0
+	for idx < len(s.recent) && !s.recent[idx].After(cutoff) {
+		idx++
+	}
+	s.recent = append(s.recent[:0], s.recent[idx:]...)
+}
+
+// expire ends the escalation, if it has expired, and must be called
+// with the lock held.
+func (s *escalatingSender) expire(now time.Time) {
+	if !s.escalated || now.Before(s.until) {
+		return
+	}
+
+	s.timer.Stop()
+	s.notify(fmt.Sprintf("restored logging to %s after escalation", s.restore), false)
+	s.Sender.SetPriority(s.restore)
+	s.escalated = false
+	s.since, s.until = time.Time{}, time.Time{}
+	s.recent = s.recent[:0]
+}
+
+func (s *escalatingSender) timeout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.expire(s.now())
+	}
+}
+
+// notify sends the notice about a change in the escalation state, and
+// must be called with the lock held, while the sender is escalated.
+func (s *escalatingSender) notify(msg string, escalated bool) {
+	m := message.NewKV().
+		KV(message.FieldsMsgName, msg).
+		KV("escalated", escalated).
+		KV("since", s.since).
+		KV("until", s.until)
+	m.SetPriority(level.Notice)
+
+	s.Sender.Send(m)
+}
+
+func (s *escalatingSender) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	return s.Sender.Close()
+}
@@ -0,0 +1,161 @@
+package send
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func newTestEscalating(opts EscalationOptions) (*escalatingSender, *InternalSender, *testClock) {
+	internal := newTestInternal()
+	s := MakeEscalating(internal, opts).(*escalatingSender)
+	return s, internal, withTestClock(&s.now)
+}
+
+func TestEscalatingSender(t *testing.T) {
+	t.Run("Escalates", func(t *testing.T) {
+		s, internal, clock := newTestEscalating(EscalationOptions{Threshold: 3, Window: time.Second, Duration: time.Hour})
+		defer s.Close()
+
+		s.Send(NewString(level.Debug, "before"))
+		for range 3 {
+			s.Send(NewString(level.Error, "failed"))
+			clock.Advance(100 * time.Millisecond)
+		}
+
+		state := s.EscalationState()
+		check.True(t, state.Escalated)
+		check.Equal(t, state.Priority, level.Info)
+		check.Equal(t, state.Recent, 3)
+		check.Equal(t, state.Until, state.Since.Add(time.Hour))
+		check.Equal(t, internal.Priority(), level.Debug)
+		check.Equal(t, s.Priority(), level.Debug)
+
+		s.Send(NewString(level.Debug, "during"))
+
+		msgs := drainLogged(internal)
+		check.Equal(t, len(msgs), 5)
+		check.Equal(t, msgs[2].Priority, level.Notice)
+		check.Substring(t, msgs[2].Rendered, "msg='escalated logging to debug")
+		check.Equal(t, msgs[4].Rendered, "during")
+
+		clock.Advance(time.Hour)
+		s.Send(NewString(level.Debug, "after"))
+
+		state = s.EscalationState()
+		check.True(t, !state.Escalated)
+		check.True(t, state.Since.IsZero())
+		check.Equal(t, state.Priority, level.Info)
+		check.Equal(t, internal.Priority(), level.Info)
+
+		msgs = drainLogged(internal)
+		check.Equal(t, len(msgs), 1)
+		check.Equal(t, msgs[0].Priority, level.Notice)
+		check.Substring(t, msgs[0].Rendered, "restored logging to info")
+	})
+	t.Run("Window", func(t *testing.T) {
+		s, internal, clock := newTestEscalating(EscalationOptions{Threshold: 3, Window: time.Second})
+		defer s.Close()
+
+		for range 10 {
+			SendContext(t.Context(), s, NewString(level.Critical, "slow"))
+			clock.Advance(600 * time.Millisecond)
+		}
+		state := s.EscalationState()
+		check.True(t, !state.Escalated)
+		check.Equal(t, state.Recent, 1)
+		check.Equal(t, len(drainLogged(internal)), 10)
+	})
+	t.Run("Extends", func(t *testing.T) {
+		s, _, clock := newTestEscalating(EscalationOptions{Threshold: 2, Window: time.Second, Duration: time.Minute})
+		defer s.Close()
+
+		s.Send(NewString(level.Error, "one"))
+		s.Send(NewString(level.Error, "two"))
+		first := s.EscalationState()
+		check.True(t, first.Escalated)
+
+		clock.Advance(30 * time.Second)
+		s.Send(NewString(level.Error, "three"))
+		s.Send(NewString(level.Error, "four"))
+		state := s.EscalationState()
+		check.Equal(t, state.Since, first.Since)
+		check.Equal(t, state.Until, first.Until.Add(30*time.Second))
+
+		clock.Advance(45 * time.Second)
+		check.True(t, s.EscalationState().Escalated)
+		clock.Advance(15 * time.Second)
+		check.True(t, !s.EscalationState().Escalated)
+	})
+	t.Run("SetPriority", func(t *testing.T) {
+		s, internal, _ := newTestEscalating(EscalationOptions{Threshold: 1, Duration: time.Minute})
+		defer s.Close()
+
+		s.Send(NewString(level.Alert, "alert"))
+		check.True(t, s.EscalationState().Escalated)
+
+		s.SetPriority(level.Warning)
+		check.Equal(t, internal.Priority(), level.Debug)
+		check.Equal(t, s.EscalationState().Priority, level.Warning)
+	})
+	t.Run("AlreadyVerbose", func(t *testing.T) {
+		s, internal, _ := newTestEscalating(EscalationOptions{Threshold: 1})
+		defer s.Close()
+		s.SetPriority(level.Trace)
+
+		s.Send(NewString(level.Error, "failed"))
+		check.True(t, !s.EscalationState().Escalated)
+		check.Equal(t, len(drainLogged(internal)), 1)
+	})
+	t.Run("Timer", func(t *testing.T) {
+		internal := MakeInternal()
+		internal.SetPriority(level.Warning)
+		s := MakeEscalating(internal, EscalationOptions{Threshold: 1, Duration: 10 * time.Millisecond})
+		defer s.Close()
+
+		s.Send(NewString(level.Error, "failed"))
+		check.Equal(t, internal.Priority(), level.Debug)
+
+		deadline := time.Now().Add(time.Second)
+		for s.EscalationState().Escalated && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		check.True(t, !s.EscalationState().Escalated)
+		check.Equal(t, internal.Priority(), level.Warning)
+	})
+	t.Run("Buffered", func(t *testing.T) {
+		internal := newTestInternal()
+		buffered := MakeBuffered(internal, time.Minute, 100)
+		s := MakeEscalating(buffered, EscalationOptions{Threshold: 2, Duration: time.Minute})
+		defer s.Close()
+
+		s.Send(NewString(level.Error, "one"))
+		s.Send(NewString(level.Error, "two"))
+		s.Send(NewString(level.Debug, "context"))
+		check.Equal(t, buffered.Priority(), level.Debug)
+		check.Equal(t, internal.Len(), 0)
+
+		check.NotError(t, s.Flush(context.Background()))
+		msgs := drainLogged(internal)
+		check.Equal(t, len(msgs), 1)
+		group, ok := msgs[0].Message.(*message.GroupComposer)
+		check.True(t, ok)
+		parts := group.Messages()
+		check.Equal(t, len(parts), 4)
+		check.Equal(t, parts[1].Priority(), level.Notice)
+		check.Equal(t, parts[3].String(), "context")
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"escalating","options":{"threshold":5,"window":"30s","duration":"2m","trigger":"critical","level":"trace"},"children":[{"type":"nop"}]}`))
+		check.NotError(t, err)
+		es := sender.(*escalatingSender)
+		check.Equal(t, es.opts, EscalationOptions{Threshold: 5, Window: 30 * time.Second, Duration: 2 * time.Minute, Trigger: level.Critical, Level: level.Trace})
+
+		_, err = BuildJSON([]byte(`{"type":"escalating","options":{"level":"loud"},"children":[{"type":"nop"}]}`))
+		check.Error(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package send
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
@@ -19,6 +20,10 @@ func WithOptionSender(s Sender, options ...message.Option) Sender {
 }
 
 func (s withOptionImpl) Send(m message.Composer) { m.SetOption(s.opts...); s.Sender.Send(m) }
+func (s withOptionImpl) SendContext(ctx context.Context, m message.Composer) {
+	m.SetOption(s.opts...)
+	SendContext(ctx, s.Sender, m)
+}
 
 const (
 	defaultFormatTmpl = "[p=%s]: %s"
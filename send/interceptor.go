@@ -1,6 +1,10 @@
 package send
 
-import "github.com/tychoish/grip/message"
+import (
+	"context"
+
+	"github.com/tychoish/grip/message"
+)
 
 type interceptor struct {
 	Sender
@@ -24,3 +28,7 @@ func MakeFilter(sender Sender, ifn func(message.Composer)) Sender {
 
 func (s *interceptor) Unwrap() Sender          { return s.Sender }
 func (s *interceptor) Send(m message.Composer) { s.filter(m); s.Sender.Send(m) }
+func (s *interceptor) SendContext(ctx context.Context, m message.Composer) {
+	s.filter(m)
+	SendContext(ctx, s.Sender, m)
+}
@@ -99,16 +99,43 @@ func MakeStandard(s Sender) *log.Logger { return log.New(MakeWriterSender(s), ""
 // library logger.
 func FromStandard(logger *log.Logger) Sender { return MakeWriter(logger.Writer()) }
 
+// ShouldLog reports if the sender should log the message: the
+// message must be loggable, and its priority must be allowed by the
+// sender (see Allows.)
 func ShouldLog(s Sender, m message.Composer) bool {
 	if m == nil || s == nil {
 		return false
 	}
-	if mp := m.Priority(); mp == level.Invalid || mp < s.Priority() {
+	if !Allows(s, m.Priority()) {
 		return false
 	}
 	return m.Loggable()
 }
 
+// FilteredSender is implemented by senders that admit a set of
+// priorities (see level.Filter) rather than all priorities above a
+// threshold, such as the senders produced by MakeMasked.
+type FilteredSender interface {
+	Sender
+	LevelFilter() level.Filter
+}
+
+// Allows reports if the sender admits messages with the priority. For
+// senders that implement FilteredSender, the filter determines which
+// priorities are admitted; otherwise the priority must be greater
+// than or equal to the sender's priority. Invalid is never admitted.
+func Allows(s Sender, p level.Priority) bool {
+	if p == level.Invalid {
+		return false
+	}
+	if fs, ok := s.(FilteredSender); ok {
+		if filter := fs.LevelFilter(); filter != nil {
+			return filter.Allows(p)
+		}
+	}
+	return p >= s.Priority()
+}
+
 type noopSender struct{ Base }
 
 // NopSender creates a valid sender implementation where all Send
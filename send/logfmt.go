@@ -0,0 +1,239 @@
+package send
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// MakeLogfmtFormatter returns a MessageFormatter that renders
+// messages as logfmt lines, as in:
+//
+//	level=info time=2024-01-02T15:04:05.999Z msg="request complete" path=/api status=200
+//
+// The time is the message's timestamp (see message.Timestamp.)
+// The message's fields follow the level, time, and message, and
+// fields with the same name as one of these are prefixed with an
+// underscore (e.g. "_level".) The fields of KV and Fields messages
+// are rendered in order, and the "msg" field (message.FieldsMsgName)
+// provides the message. For other messages, the message is the
+// string form, and the fields come from the Raw form, when it is a
+// document (e.g. annotations;) nested documents are flattened into
+// dotted keys. Values are quoted when needed, and quoted values
+// escape quotes, backslashes, and control characters.
+//
+// Use message.ParseLogfmt and MakeLogfmtWriterSender to read logfmt
+// lines back into messages.
+func MakeLogfmtFormatter() MessageFormatter {
+	return func(m message.Composer) (string, error) {
+		// rendering the fields first collects the message's
+		// metadata, including its timestamp.
+		msg, fields := logfmtFields(m)
+
+		var buf strings.Builder
+		writeLogfmtPair(&buf, "level", m.Priority().String())
+		writeLogfmtPair(&buf, "time", message.Timestamp(m).Format(time.RFC3339Nano))
+
+		if msg != "" {
+			writeLogfmtPair(&buf, message.FieldsMsgName, msg)
+		}
+		for key, value := range fields {
+			for key == "level" || key == "time" || key == message.FieldsMsgName {
+				key = "_" + key
+			}
+			writeLogfmtPair(&buf, key, logfmtValue(value))
+		}
+
+		return buf.String(), nil
+	}
+}
+
+// logfmtFields returns the message and the structured fields of the
+// message.
+func logfmtFields(m message.Composer) (string, iter.Seq2[string, any]) {
+	switch raw := m.Raw().(type) {
+	case interface{ Iterator() iter.Seq2[string, any] }:
+		var msg string
+		for key, value := range raw.Iterator() {
+			if key == message.FieldsMsgName {
+				msg = logfmtValue(value)
+				break
+			}
+		}
+		// the documents of error messages hold only their
+		// annotations.
+		if _, isErr := m.(error); isErr && msg == "" {
+			msg = m.String()
+		}
+		return msg, func(yield func(string, any) bool) {
+			for key, value := range raw.Iterator() {
+				if key == message.FieldsMsgName || key == "meta" {
+					continue
+				}
+				if !yield(key, value) {
+					return
+				}
+			}
+		}
+	case map[string]any:
+		return documentLogfmt(m.String(), maps.Clone(raw))
+	}
+
+	if !m.Structured() {
+		return m.String(), func(func(string, any) bool) {}
+	}
+
+	// round trip other documents through JSON, to collect the
+	// fields (and annotations) of arbitrary types.
+	var doc map[string]any
+	if out, err := json.Marshal(m.Raw()); err == nil && json.Unmarshal(out, &doc) == nil {
+		return documentLogfmt(m.String(), doc)
+	}
+	return m.String(), func(func(string, any) bool) {}
+}
+
+// documentLogfmt returns the fields of a document, which it may
+// modify: the message is omitted when it is the same as the string
+// form, and the annotations of string messages (the "context"
+// document) are fields of the message, as in envelopes.
+func documentLogfmt(msg string, doc map[string]any) (string, iter.Seq2[string, any]) {
+	if inner, ok := doc[message.FieldsMsgName].(string); ok && inner == msg {
+		delete(doc, message.FieldsMsgName)
+	}
+	if annotations, ok := doc["context"].(map[string]any); ok {
+		delete(doc, "context")
+		for key, value := range annotations {
+			if _, ok := doc[key]; !ok {
+				doc[key] = value
+			}
+		}
+	}
+	return msg, flattenLogfmt("", doc)
+}
+
+// flattenLogfmt iterates over the document, in key order, rendering
+// nested documents as dotted keys.
+func flattenLogfmt(prefix string, doc map[string]any) iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, key := range slices.Sorted(maps.Keys(doc)) {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			if nested, ok := doc[key].(map[string]any); ok {
+				for k, v := range flattenLogfmt(name, nested) {
+					if !yield(k, v) {
+						return
+					}
+				}
+				continue
+			}
+			if !yield(name, doc[key]) {
+				return
+			}
+		}
+	}
+}
+
+func logfmtValue(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case level.Priority:
+		return val.String()
+	case error:
+		return val.Error()
+	case fmt.Stringer:
+		return val.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(val)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+
+	if out, err := json.Marshal(value); err == nil {
+		return string(out)
+	}
+	return fmt.Sprint(value)
+}
+
+func writeLogfmtPair(buf *strings.Builder, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	writeLogfmtKey(buf, key)
+	buf.WriteByte('=')
+	writeLogfmtString(buf, value)
+}
+
+// writeLogfmtKey writes the key, replacing the characters that are
+// not valid in logfmt keys with underscores.
+func writeLogfmtKey(buf *strings.Builder, key string) {
+	if key == "" {
+		buf.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buf.WriteByte('_')
+			continue
+		}
+		buf.WriteRune(r)
+	}
+}
+
+func logfmtNeedsQuotes(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func writeLogfmtString(buf *strings.Builder, value string) {
+	if !logfmtNeedsQuotes(value) {
+		buf.WriteString(value)
+		return
+	}
+
+	buf.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
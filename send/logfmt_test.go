@@ -0,0 +1,154 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+var logfmtPrefix = regexp.MustCompile(`^level=(\w+) time=\S+ `)
+
+func TestLogfmtFormatter(t *testing.T) {
+	format := MakeLogfmtFormatter()
+	render := func(t *testing.T, m message.Composer) string {
+		t.Helper()
+		out, err := format(m)
+		check.NotError(t, err)
+		check.True(t, logfmtPrefix.MatchString(out))
+		return logfmtPrefix.ReplaceAllString(out, "")
+	}
+
+	t.Run("KV", func(t *testing.T) {
+		m := message.NewKV().
+			KV("msg", "request complete").
+			KV("path", "/api").
+			KV("status", 200).
+			KV("ratio", 0.5).
+			KV("err", errors.New("bad \"input\"")).
+			KV("tags", []string{"a", "b"}).
+			KV("empty", "").
+			KV("key with=space", "line\nbreak")
+		m.SetPriority(level.Info)
+		check.Equal(t, render(t, m),
+			`msg="request complete" path=/api status=200 ratio=0.5 err="bad \"input\"" tags="[\"a\",\"b\"]" empty="" key_with_space="line\nbreak"`)
+	})
+	t.Run("Fields", func(t *testing.T) {
+		m := message.MakeFields(message.Fields{"msg": "hello", "user": "jo"})
+		m.SetPriority(level.Warning)
+		out, err := format(m)
+		check.NotError(t, err)
+		check.Substring(t, out, "level=warning ")
+		check.True(t, strings.HasSuffix(out, " msg=hello user=jo"))
+	})
+	t.Run("Annotations", func(t *testing.T) {
+		m := message.MakeString("plain message")
+		m.SetPriority(level.Error)
+		m.Annotate("user", "jo")
+		m.Annotate("attempt", 3)
+		check.Equal(t, render(t, m), `msg="plain message" attempt=3 user=jo`)
+	})
+	t.Run("Collisions", func(t *testing.T) {
+		m := message.NewKV().KV("msg", "hi").KV("level", "custom").KV("time", "yesterday")
+		m.SetPriority(level.Info)
+		check.Equal(t, render(t, m), `msg=hi _level=custom _time=yesterday`)
+
+		str := message.MakeString("hi")
+		str.SetPriority(level.Info)
+		str.Annotate("level", "custom")
+		check.Equal(t, render(t, str), `msg=hi _level=custom`)
+
+		out, err := format(m)
+		check.NotError(t, err)
+		parsed, err := message.ParseLogfmt(out)
+		check.NotError(t, err)
+		check.Equal(t, parsed.Priority(), level.Info)
+	})
+	t.Run("Error", func(t *testing.T) {
+		m := message.MakeError(errors.New("connection refused"))
+		m.SetPriority(level.Error)
+		check.Equal(t, render(t, m), `msg="connection refused"`)
+		m.Annotate("host", "db1")
+		check.Equal(t, render(t, m), `msg="connection refused" host=db1`)
+	})
+	t.Run("Unstructured", func(t *testing.T) {
+		m := message.MakeString("x=1 \\ done")
+		m.SetPriority(level.Debug)
+		check.Equal(t, render(t, m), `msg="x=1 \\ done"`)
+	})
+	t.Run("RoundTrip", func(t *testing.T) {
+		m := message.NewKV().KV("msg", "quote \" and \\ and\ttab").KV("n", 42).KV("ctl", "\x01")
+		m.SetPriority(level.Notice)
+		out, err := format(m)
+		check.NotError(t, err)
+
+		parsed, err := message.ParseLogfmt(out)
+		check.NotError(t, err)
+		check.Equal(t, parsed.Priority(), level.Notice)
+		fields := map[string]any{}
+		for k, v := range parsed.Iterator() {
+			fields[k] = v
+		}
+		check.Equal(t, fields["msg"], "quote \" and \\ and\ttab")
+		check.Equal(t, fields["n"], "42")
+		check.Equal(t, fields["ctl"], "\x01")
+		_, hasTime := fields["time"]
+		check.True(t, hasTime)
+	})
+	t.Run("Timestamp", func(t *testing.T) {
+		m := message.MakeString("collected")
+		m.SetPriority(level.Info)
+		m.SetOption(message.OptionCollectInfo)
+		out := render(t, m)
+		check.Equal(t, out, "msg=collected")
+
+		ts := m.(interface{ Timestamp() time.Time }).Timestamp()
+		full, err := format(m)
+		check.NotError(t, err)
+		check.Substring(t, full, " time="+ts.Format(time.RFC3339Nano)+" ")
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"nop","formatter":"logfmt"}`))
+		check.NotError(t, err)
+		out, err := sender.GetFormatter()(NewString(level.Info, "hi"))
+		check.NotError(t, err)
+		check.True(t, strings.HasSuffix(out, " msg=hi"))
+	})
+}
+
+func TestLogfmtWriterSender(t *testing.T) {
+	internal := MakeInternal()
+	internal.SetPriority(level.Debug)
+	writer := MakeLogfmtWriterSender(internal)
+	writer.Store(level.Info)
+
+	_, err := fmt.Fprintln(writer, `level=error msg="it broke" code=7`)
+	check.NotError(t, err)
+	_, err = fmt.Fprint(writer, "user=jo action=login\nnot \"logfmt\nconnection refused\n")
+	check.NotError(t, err)
+
+	msg := internal.GetMessage()
+	check.Equal(t, msg.Priority, level.Error)
+	check.Equal(t, msg.Rendered, "msg='it broke' code='7'")
+
+	msg = internal.GetMessage()
+	check.Equal(t, msg.Priority, level.Info)
+	_, ok := msg.Message.(*message.KV)
+	check.True(t, ok)
+	check.Equal(t, msg.Rendered, "user='jo' action='login'")
+
+	msg = internal.GetMessage()
+	check.Equal(t, msg.Priority, level.Info)
+	check.Equal(t, msg.Rendered, "not \"logfmt")
+
+	// prose is a message, not a series of keys
+	msg = internal.GetMessage()
+	check.Equal(t, msg.Priority, level.Info)
+	check.Equal(t, msg.Rendered, "connection refused")
+}
@@ -0,0 +1,55 @@
+package send
+
+import (
+	"context"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+type maskedSender struct {
+	Sender
+	filter level.Filter
+}
+
+// MakeMasked wraps a sender so that it only sends messages with the
+// priorities that the filter allows, instead of all messages above a
+// threshold. Use a level.Mask to select specific levels (e.g. only
+// Notice and Alert) or a level.Range to set a ceiling in addition to
+// a minimum:
+//
+//	send.MakeMasked(pager, level.MaskOf(level.Notice, level.Alert))
+//	send.MakeMasked(debug, level.Range{Max: level.Debug})
+//
+// The constructor sets the priority of the underlying sender to the
+// lowest priority that the filter allows, so that the underlying
+// sender does not drop the messages that pass the filter; changing
+// the priority of the masked sender later changes the underlying
+// sender's threshold, which continues to apply.
+//
+// The masked sender owns the underlying sender, and closing the
+// masked sender closes the underlying sender.
+func MakeMasked(sender Sender, filter level.Filter) Sender {
+	for p := range 256 {
+		if filter.Allows(level.Priority(p)) {
+			sender.SetPriority(level.Priority(p))
+			break
+		}
+	}
+	return &maskedSender{Sender: sender, filter: filter}
+}
+
+func (s *maskedSender) Unwrap() Sender            { return s.Sender }
+func (s *maskedSender) LevelFilter() level.Filter { return s.filter }
+
+func (s *maskedSender) Send(m message.Composer) {
+	if ShouldLog(s, m) {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *maskedSender) SendContext(ctx context.Context, m message.Composer) {
+	if ShouldLog(s, m) {
+		SendContext(ctx, s.Sender, m)
+	}
+}
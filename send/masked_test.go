@@ -0,0 +1,69 @@
+package send
+
+import (
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+)
+
+func TestMaskedSender(t *testing.T) {
+	t.Run("Mask", func(t *testing.T) {
+		internal := MakeInternal()
+		s := MakeMasked(internal, level.MaskOf(level.Notice, level.Alert))
+		check.Equal(t, internal.Priority(), level.Notice)
+
+		for _, p := range []level.Priority{level.Emergency, level.Alert, level.Error, level.Notice, level.Info} {
+			s.Send(NewString(p, p.String()))
+		}
+		SendContext(t.Context(), s, NewString(level.Alert, "context"))
+		SendContext(t.Context(), s, NewString(level.Critical, "context"))
+
+		check.Equal(t, internal.Len(), 3)
+		var logged []level.Priority
+		for internal.HasMessage() {
+			msg := internal.GetMessage()
+			check.True(t, msg.Logged)
+			logged = append(logged, msg.Priority)
+		}
+		check.EqualItems(t, logged, []level.Priority{level.Alert, level.Notice, level.Alert})
+	})
+	t.Run("Range", func(t *testing.T) {
+		internal := MakeInternal()
+		s := MakeMasked(internal, level.Range{Max: level.Debug})
+		check.Equal(t, internal.Priority(), level.Priority(1))
+
+		check.True(t, Allows(s, level.Trace))
+		check.True(t, Allows(s, level.Debug))
+		check.True(t, !Allows(s, level.Info))
+		check.True(t, !Allows(s, level.Invalid))
+		check.True(t, ShouldLog(s, NewString(level.Debug, "debug")))
+		check.True(t, !ShouldLog(s, NewString(level.Error, "error")))
+	})
+	t.Run("Allows", func(t *testing.T) {
+		internal := MakeInternal()
+		internal.SetPriority(level.Info)
+		check.True(t, Allows(internal, level.Info))
+		check.True(t, !Allows(internal, level.Debug))
+		check.True(t, !Allows(internal, level.Invalid))
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"masked","options":{"levels":["notice","alert"]},"children":[{"type":"nop"}]}`))
+		check.NotError(t, err)
+		check.Equal(t, sender.(FilteredSender).LevelFilter(), level.Filter(level.MaskOf(level.Notice, level.Alert)))
+
+		sender, err = BuildJSON([]byte(`{"type":"masked","options":{"min":"info","max":"error"},"children":[{"type":"nop"}]}`))
+		check.NotError(t, err)
+		check.Equal(t, sender.(FilteredSender).LevelFilter(), level.Filter(level.Range{Min: level.Info, Max: level.Error}))
+
+		for _, doc := range []string{
+			`{"type":"masked","children":[{"type":"nop"}]}`,
+			`{"type":"masked","options":{"levels":["loud"]},"children":[{"type":"nop"}]}`,
+			`{"type":"masked","options":{"max":"loud"},"children":[{"type":"nop"}]}`,
+			`{"type":"masked","options":{"levels":["info"],"min":"info"},"children":[{"type":"nop"}]}`,
+		} {
+			_, err = BuildJSON([]byte(doc))
+			check.Error(t, err)
+		}
+	})
+}
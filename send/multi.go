@@ -58,11 +58,7 @@ func AddToMulti(multi Sender, s Sender) error {
 		sender.add(s)
 		return nil
 	case *asyncGroupSender:
-		if err := sender.senders.PushBack(s); err != nil {
-			return err
-		}
-		sender.startSenderWorker(s)
-		return nil
+		return sender.startSenderWorker(s)
 	default:
 		return fmt.Errorf("%s is not a multi sender", multi.Name())
 	}
@@ -111,6 +107,14 @@ func (s *multiSender) Send(m message.Composer) {
 	}
 }
 
+func (s *multiSender) SendContext(ctx context.Context, m message.Composer) {
+	if ShouldLog(s, m) {
+		for _, sender := range s.senders {
+			SendContext(ctx, sender, m)
+		}
+	}
+}
+
 func (s *multiSender) Flush(ctx context.Context) error {
 	catcher := &erc.Collector{}
 
@@ -0,0 +1,156 @@
+package send
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// RecorderOptions configures a flight recorder (see
+// MakeFlightRecorder.)
+type RecorderOptions struct {
+	// Size is the maximum number of messages in the
+	// backlog. Defaults to 1000.
+	Size int
+	// Age is the maximum age of the messages in the backlog. When
+	// Age is zero, messages are only discarded when the backlog
+	// is full.
+	Age time.Duration
+	// Threshold is the lowest priority that is sent immediately,
+	// rather than recorded. Defaults to the priority of the
+	// wrapped sender.
+	Threshold level.Priority
+	// Trigger is the lowest priority that flushes the
+	// backlog. Defaults to level.Error.
+	Trigger level.Priority
+}
+
+type recordedMessage struct {
+	msg  message.Composer
+	time time.Time
+}
+
+type flightRecorder struct {
+	Sender
+	threshold level.Priority
+	trigger   level.Priority
+	age       time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	ring  []recordedMessage
+	start int
+	count int
+}
+
+// MakeFlightRecorder wraps a sender so that messages below the
+// threshold are kept in memory, in a ring buffer, rather than
+// discarded. When a message at or above the trigger priority arrives,
+// the recorder sends the backlog of recorded messages, in order,
+// followed by the triggering message, and then clears the
+// backlog. Messages at or above the threshold are sent immediately.
+//
+// The constructor sets the priority of the wrapped sender to Trace,
+// so that the recorder retains all messages; use SetPriority on the
+// recorder to change the lowest priority that it records. As with
+// InMemorySender, the backlog holds the message objects themselves.
+//
+// The recorder owns the wrapped sender, and closing the recorder
+// discards the backlog and closes the wrapped sender.
+func MakeFlightRecorder(sender Sender, opts RecorderOptions) Sender {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.Threshold == level.Invalid {
+		opts.Threshold = sender.Priority()
+	}
+	if opts.Trigger == level.Invalid {
+		opts.Trigger = level.Error
+	}
+
+	sender.SetPriority(level.Trace)
+
+	return &flightRecorder{
+		Sender:    sender,
+		threshold: opts.Threshold,
+		trigger:   opts.Trigger,
+		age:       opts.Age,
+		now:       time.Now,
+		ring:      make([]recordedMessage, opts.Size),
+	}
+}
+
+func (s *flightRecorder) Unwrap() Sender { return s.Sender }
+
+func (s *flightRecorder) Send(m message.Composer) {
+	s.dispatch(m, func(m message.Composer) { s.Sender.Send(m) })
+}
+
+func (s *flightRecorder) SendContext(ctx context.Context, m message.Composer) {
+	s.dispatch(m, func(m message.Composer) { SendContext(ctx, s.Sender, m) })
+}
+
+func (s *flightRecorder) dispatch(m message.Composer, send func(message.Composer)) {
+	if !ShouldLog(s, m) {
+		return
+	}
+
+	switch p := m.Priority(); {
+	case p >= s.trigger:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.expire(s.now())
+		for s.count > 0 {
+			send(s.pop())
+		}
+		send(m)
+	case p >= s.threshold:
+		send(m)
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		now := s.now()
+		s.expire(now)
+		if s.count == len(s.ring) {
+			s.pop()
+		}
+		s.ring[(s.start+s.count)%len(s.ring)] = recordedMessage{msg: m, time: now}
+		s.count++
+	}
+}
+
+// pop removes and returns the oldest message in the backlog, and must
+// be called with the lock held.
+func (s *flightRecorder) pop() message.Composer {
+	rec := s.ring[s.start]
+	s.ring[s.start] = recordedMessage{}
+	s.start = (s.start + 1) % len(s.ring)
+	s.count--
+	return rec.msg
+}
+
+// expire discards the messages that are older than the maximum age,
+// and must be called with the lock held.
+func (s *flightRecorder) expire(now time.Time) {
+	if s.age <= 0 {
+		return
+	}
+	for s.count > 0 && now.Sub(s.ring[s.start].time) > s.age {
+		s.pop()
+	}
+}
+
+func (s *flightRecorder) Close() error {
+	s.mu.Lock()
+	for s.count > 0 {
+		s.pop()
+	}
+	s.mu.Unlock()
+
+	return s.Sender.Close()
+}
@@ -0,0 +1,79 @@
+package send
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+)
+
+func newTestRecorder(opts RecorderOptions) (*flightRecorder, *InternalSender, *testClock) {
+	internal := newTestInternal()
+	s := MakeFlightRecorder(internal, opts).(*flightRecorder)
+	return s, internal, withTestClock(&s.now)
+}
+
+func TestFlightRecorder(t *testing.T) {
+	t.Run("Backlog", func(t *testing.T) {
+		s, internal, _ := newTestRecorder(RecorderOptions{})
+		check.Equal(t, s.threshold, level.Info)
+		check.Equal(t, s.Priority(), level.Trace)
+
+		s.Send(NewString(level.Debug, "one"))
+		s.Send(NewString(level.Info, "info"))
+		s.Send(NewString(level.Trace, "two"))
+		SendContext(t.Context(), s, NewString(level.Debug, "three"))
+		check.EqualItems(t, renderedLogged(internal), []string{"info"})
+
+		s.Send(NewString(level.Error, "failed"))
+		check.EqualItems(t, renderedLogged(internal), []string{"one", "two", "three", "failed"})
+
+		s.Send(NewString(level.Critical, "again"))
+		check.EqualItems(t, renderedLogged(internal), []string{"again"})
+	})
+	t.Run("Size", func(t *testing.T) {
+		s, internal, _ := newTestRecorder(RecorderOptions{Size: 3})
+		for i := range 10 {
+			s.Send(NewString(level.Debug, fmt.Sprint(i)))
+		}
+		s.Send(NewString(level.Alert, "alert"))
+		check.EqualItems(t, renderedLogged(internal), []string{"7", "8", "9", "alert"})
+	})
+	t.Run("Age", func(t *testing.T) {
+		s, internal, clock := newTestRecorder(RecorderOptions{Age: time.Minute})
+		s.Send(NewString(level.Debug, "old"))
+		clock.Advance(45 * time.Second)
+		s.Send(NewString(level.Debug, "new"))
+		clock.Advance(30 * time.Second)
+		s.Send(NewString(level.Error, "failed"))
+		check.EqualItems(t, renderedLogged(internal), []string{"new", "failed"})
+	})
+	t.Run("Options", func(t *testing.T) {
+		s, internal, _ := newTestRecorder(RecorderOptions{Threshold: level.Warning, Trigger: level.Critical})
+		s.SetPriority(level.Debug)
+		s.Send(NewString(level.Trace, "dropped"))
+		s.Send(NewString(level.Info, "recorded"))
+		s.Send(NewString(level.Error, "error"))
+		check.EqualItems(t, renderedLogged(internal), []string{"error"})
+		s.Send(NewString(level.Critical, "critical"))
+		check.EqualItems(t, renderedLogged(internal), []string{"recorded", "critical"})
+	})
+	t.Run("Close", func(t *testing.T) {
+		s, internal, _ := newTestRecorder(RecorderOptions{})
+		s.Send(NewString(level.Debug, "discarded"))
+		check.NotError(t, s.Close())
+		check.Equal(t, s.count, 0)
+		check.Equal(t, internal.Len(), 0)
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"recorder","options":{"size":10,"age":"1m","threshold":"warning","trigger":"alert"},"children":[{"type":"nop"}]}`))
+		check.NotError(t, err)
+		rec := sender.(*flightRecorder)
+		check.Equal(t, len(rec.ring), 10)
+		check.Equal(t, rec.age, time.Minute)
+		check.Equal(t, rec.threshold, level.Warning)
+		check.Equal(t, rec.trigger, level.Alert)
+	})
+}
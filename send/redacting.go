@@ -0,0 +1,40 @@
+package send
+
+import (
+	"context"
+
+	"github.com/tychoish/grip/message"
+)
+
+type redactingSender struct {
+	Sender
+	redactor *message.Redactor
+}
+
+// MakeRedacting wraps a sender so that every message is redacted (see
+// message.Redact) before the underlying sender, and its formatter,
+// render the message. Use message.DefaultRedactor for the common
+// cases, or configure a message.Redactor. Callers should not modify
+// the redactor after calling the constructor.
+//
+// As with the annotating sender, changes to the redacting sender
+// (level, formatter, error handler) propagate to the underlying
+// sender, and closing the redacting sender closes the underlying
+// sender.
+func MakeRedacting(sender Sender, redactor *message.Redactor) Sender {
+	return &redactingSender{Sender: sender, redactor: redactor}
+}
+
+func (s *redactingSender) Unwrap() Sender { return s.Sender }
+
+func (s *redactingSender) Send(m message.Composer) {
+	if ShouldLog(s, m) {
+		s.Sender.Send(message.Redact(m, s.redactor))
+	}
+}
+
+func (s *redactingSender) SendContext(ctx context.Context, m message.Composer) {
+	if ShouldLog(s, m) {
+		SendContext(ctx, s.Sender, message.Redact(m, s.redactor))
+	}
+}
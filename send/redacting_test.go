@@ -0,0 +1,59 @@
+package send
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestRedactingSender(t *testing.T) {
+	internal := MakeInternal()
+	internal.SetPriority(level.Info)
+	s := MakeRedacting(internal, message.DefaultRedactor())
+
+	m := message.MakeFields(message.Fields{"user": "jo", "password": "hunter2"})
+	m.SetPriority(level.Info)
+	s.Send(m)
+	SendContext(t.Context(), s, NewString(level.Info, "token: Bearer abc123"))
+
+	msg := internal.GetMessage()
+	check.True(t, msg.Logged)
+	check.Substring(t, msg.Rendered, "password='[REDACTED]'")
+	check.True(t, !strings.Contains(msg.Rendered, "hunter2"))
+
+	msg = internal.GetMessage()
+	check.Equal(t, msg.Rendered, "token: [REDACTED]")
+
+	s.Send(NewString(level.Debug, "below the threshold"))
+	check.Equal(t, internal.Len(), 0)
+
+	t.Run("Formatter", func(t *testing.T) {
+		internal := MakeInternal()
+		internal.SetPriority(level.Info)
+		s := MakeRedacting(internal, message.DefaultRedactor())
+		s.SetFormatter(MakeJSONFormatter())
+
+		m := message.NewKV().KV("secret", "s3").KV("ok", true)
+		m.SetPriority(level.Info)
+		s.Send(m)
+
+		out, err := internal.GetFormatter()(internal.GetMessage().Message)
+		check.NotError(t, err)
+		check.True(t, !strings.Contains(out, "s3"))
+		check.Substring(t, out, "[REDACTED]")
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"redacting","options":{"defaults":true,"keys":["ssn"],"values":["\\d{3}-\\d{2}-\\d{4}"],"mask":"***"},"children":[{"type":"nop"}]}`))
+		check.NotError(t, err)
+		redactor := sender.(*redactingSender).redactor
+		check.True(t, redactor.MatchesKey("ssn"))
+		check.True(t, redactor.MatchesKey("password"))
+		check.Equal(t, redactor.Text("id 123-45-6789"), "id ***")
+
+		_, err = BuildJSON([]byte(`{"type":"redacting","options":{"values":["("]},"children":[{"type":"nop"}]}`))
+		check.Error(t, err)
+	})
+}
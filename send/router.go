@@ -0,0 +1,147 @@
+package send
+
+import (
+	"context"
+	"slices"
+
+	"github.com/tychoish/fun/erc"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// Route describes one destination of a router sender (see
+// MakeRouter.) A message matches the route when its priority is
+// within the inclusive range between Min and Max, and the Match
+// predicate, if specified, returns true. Either end of the range may
+// be level.Invalid, which leaves that end of the range unbounded.
+type Route struct {
+	Min    level.Priority
+	Max    level.Priority
+	Match  func(message.Composer) bool
+	Sender Sender
+}
+
+// Matches reports if the message matches the route.
+func (r Route) Matches(m message.Composer) bool {
+	p := m.Priority()
+	switch {
+	case r.Min != level.Invalid && p < r.Min:
+		return false
+	case r.Max != level.Invalid && p > r.Max:
+		return false
+	case r.Match != nil:
+		return r.Match(m)
+	default:
+		return true
+	}
+}
+
+type routerSender struct {
+	routes []Route
+	def    Sender
+	Base
+}
+
+// MakeRouter constructs a sender that dispatches each message to the
+// senders of all of the routes that match it, in order, and to the
+// default sender if no route matches. Senders used by several
+// matching routes receive the message once. The default sender may be nil,
+// in which case messages that do not match any route are dropped.
+// For example:
+//
+//	send.MakeRouter(nil,
+//		send.Route{Min: level.Error, Sender: stderr},
+//		send.Route{Min: level.Info, Max: level.Info, Sender: file},
+//		send.Route{Max: level.Trace, Sender: buffer},
+//	)
+//
+// The router's own priority is checked before routing, while each
+// destination applies its own threshold. Unlike multi senders,
+// setting the router's priority or formatter does not change the
+// destinations; the name and error handler propagate to all
+// destinations.
+//
+// The router takes ownership of the destination senders, so closing
+// the router closes all of them.
+func MakeRouter(def Sender, routes ...Route) Sender {
+	return &routerSender{routes: routes, def: def}
+}
+
+func (s *routerSender) Send(m message.Composer) {
+	s.dispatch(m, func(sender Sender) { sender.Send(m) })
+}
+
+func (s *routerSender) SendContext(ctx context.Context, m message.Composer) {
+	s.dispatch(m, func(sender Sender) { SendContext(ctx, sender, m) })
+}
+
+func (s *routerSender) dispatch(m message.Composer, send func(Sender)) {
+	if !ShouldLog(s, m) {
+		return
+	}
+
+	var sent []Sender
+	for _, route := range s.routes {
+		if route.Matches(m) && !slices.Contains(sent, route.Sender) {
+			sent = append(sent, route.Sender)
+			send(route.Sender)
+		}
+	}
+
+	if len(sent) == 0 && s.def != nil {
+		send(s.def)
+	}
+}
+
+// senders returns each destination once, even if it's used by several
+// routes.
+func (s *routerSender) senders() []Sender {
+	out := make([]Sender, 0, len(s.routes)+1)
+	add := func(sender Sender) {
+		if sender == nil {
+			return
+		}
+		for _, existing := range out {
+			if existing == sender {
+				return
+			}
+		}
+		out = append(out, sender)
+	}
+
+	for _, route := range s.routes {
+		add(route.Sender)
+	}
+	add(s.def)
+	return out
+}
+
+func (s *routerSender) SetName(n string) {
+	s.Base.SetName(n)
+	for _, sender := range s.senders() {
+		sender.SetName(n)
+	}
+}
+
+func (s *routerSender) SetErrorHandler(errh ErrorHandler) {
+	s.Base.SetErrorHandler(errh)
+	for _, sender := range s.senders() {
+		sender.SetErrorHandler(errh)
+	}
+}
+
+func (s *routerSender) Flush(ctx context.Context) error {
+	catcher := &erc.Collector{}
+	for _, sender := range s.senders() {
+		catcher.Push(sender.Flush(ctx))
+	}
+	return catcher.Resolve()
+}
+
+func (s *routerSender) Close() error {
+	catcher := &erc.Collector{}
+	for _, sender := range s.senders() {
+		catcher.Push(sender.Close())
+	}
+	return catcher.Resolve()
+}
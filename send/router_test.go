@@ -0,0 +1,129 @@
+package send
+
+import (
+	"testing"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestRouterSender(t *testing.T) {
+	newSink := func() *InternalSender {
+		s := MakeInternal()
+		s.SetPriority(level.Trace)
+		return s
+	}
+
+	t.Run("Ranges", func(t *testing.T) {
+		errs, info, trace, def := newSink(), newSink(), newSink(), newSink()
+		router := MakeRouter(def,
+			Route{Min: level.Error, Sender: errs},
+			Route{Min: level.Info, Max: level.Info, Sender: info},
+			Route{Max: level.Trace, Sender: trace},
+		)
+
+		for _, p := range []level.Priority{level.Emergency, level.Error, level.Warning, level.Info, level.Debug, level.Trace} {
+			router.Send(NewString(p, p.String()))
+		}
+
+		check.Equal(t, errs.Len(), 2)
+		check.Equal(t, info.Len(), 1)
+		check.Equal(t, trace.Len(), 1)
+		check.Equal(t, def.Len(), 2)
+		check.Equal(t, def.GetMessage().Priority, level.Warning)
+		check.Equal(t, def.GetMessage().Priority, level.Debug)
+	})
+	t.Run("Predicates", func(t *testing.T) {
+		audit, rest := newSink(), newSink()
+		router := MakeRouter(rest, Route{
+			Match: func(m message.Composer) bool {
+				kv, ok := m.(*message.KV)
+				if !ok {
+					return false
+				}
+				for k := range kv.Iterator() {
+					if k == "audit" {
+						return true
+					}
+				}
+				return false
+			},
+			Sender: audit,
+		})
+
+		router.Send(message.NewKV().KV("audit", true).KV("user", "alice").Level(level.Info))
+		SendContext(t.Context(), router, NewString(level.Info, "hello"))
+		check.Equal(t, audit.Len(), 1)
+		check.Equal(t, rest.Len(), 1)
+	})
+	t.Run("MultipleRoutes", func(t *testing.T) {
+		all, errs := newSink(), newSink()
+		router := MakeRouter(nil, Route{Sender: all}, Route{Min: level.Error, Sender: errs})
+		router.Send(NewString(level.Error, "both"))
+		router.Send(NewString(level.Info, "one"))
+		check.Equal(t, all.Len(), 2)
+		check.Equal(t, errs.Len(), 1)
+	})
+	t.Run("SharedSender", func(t *testing.T) {
+		shared, other := newSink(), newSink()
+		router := MakeRouter(nil,
+			Route{Min: level.Error, Sender: shared},
+			Route{Min: level.Warning, Sender: other},
+			Route{Match: func(m message.Composer) bool { return true }, Sender: shared},
+		)
+		router.Send(NewString(level.Error, "once"))
+		SendContext(t.Context(), router, NewString(level.Critical, "once"))
+		router.Send(NewString(level.Info, "predicate"))
+		check.Equal(t, shared.Len(), 3)
+		check.Equal(t, other.Len(), 2)
+	})
+	t.Run("NoDefault", func(t *testing.T) {
+		errs := newSink()
+		router := MakeRouter(nil, Route{Min: level.Error, Sender: errs})
+		router.Send(NewString(level.Info, "dropped"))
+		check.Equal(t, errs.Len(), 0)
+	})
+	t.Run("Threshold", func(t *testing.T) {
+		def := newSink()
+		router := MakeRouter(def)
+		router.SetPriority(level.Warning)
+		router.Send(NewString(level.Info, "dropped"))
+		router.Send(NewString(level.Warning, "sent"))
+		check.Equal(t, def.Len(), 1)
+		check.Equal(t, def.Priority(), level.Trace)
+	})
+	t.Run("Lifecycle", func(t *testing.T) {
+		shared, def := NopSender(), NopSender()
+		router := MakeRouter(def, Route{Min: level.Error, Sender: shared}, Route{Max: level.Debug, Sender: shared})
+		router.SetName("router")
+		check.Equal(t, shared.Name(), "router")
+		check.Equal(t, def.Name(), "router")
+		check.NotError(t, router.Flush(t.Context()))
+		check.NotError(t, router.Close())
+	})
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{
+			"type": "router",
+			"options": {"routes": [{"min": "error"}, {"max": "debug"}], "default": true},
+			"children": [{"type": "inmemory"}, {"type": "inmemory"}, {"type": "inmemory"}]
+		}`))
+		check.NotError(t, err)
+
+		router := sender.(*routerSender)
+		for _, child := range router.senders() {
+			child.SetPriority(level.Trace)
+		}
+		router.Send(NewString(level.Alert, "error"))
+		router.Send(NewString(level.Trace, "trace"))
+		router.Send(NewString(level.Info, "info"))
+		for _, child := range router.senders() {
+			check.Equal(t, len(child.(*InMemorySender).Get()), 1)
+		}
+
+		_, err = BuildJSON([]byte(`{"type": "router", "options": {"routes": [{"min": "bogus"}]}, "children": [{"type": "nop"}]}`))
+		check.Error(t, err)
+		_, err = BuildJSON([]byte(`{"type": "router", "options": {"default": true}}`))
+		check.Error(t, err)
+	})
+}
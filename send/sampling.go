@@ -0,0 +1,283 @@
+package send
+
+import (
+	"context"
+	"maps"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// SamplingOptions configures a Sampler. Sampling and rate limiting
+// are both applied per key, and a message must pass both to be
+// sent. The zero value does not suppress any messages.
+type SamplingOptions struct {
+	// First is the number of messages with each key that are
+	// sent during each interval before sampling begins. When
+	// First is zero, messages are only sampled if Thereafter is
+	// set.
+	First int
+	// Thereafter controls the sampling of messages after the
+	// first: one of every Thereafter messages with each key is
+	// sent. When First is set and Thereafter is zero, all
+	// messages after the first are suppressed.
+	Thereafter int
+	// Rate limits the messages with each key to Rate messages per
+	// second, using a token bucket that holds up to Burst
+	// tokens. A zero Rate disables rate limiting. Burst defaults
+	// to the larger of 1 and the Rate.
+	Rate  float64
+	Burst int
+	// Interval is the period after which the First and Thereafter
+	// counters reset, and at which the summaries of suppressed
+	// messages are produced. Defaults to one minute.
+	Interval time.Duration
+	// SummaryPriority is the priority of the summary messages. By
+	// default, summaries have the highest priority of the
+	// messages they summarize.
+	SummaryPriority level.Priority
+	// Key returns the key used to group messages. By default
+	// messages are grouped by SamplingKey.
+	Key func(message.Composer) string
+}
+
+// SamplingKey returns the key used to group messages when sampling:
+// messages created with a template (e.g. message.MakeFormat) are
+// grouped by the template, and other messages by their string form.
+func SamplingKey(m message.Composer) string {
+	if t, ok := m.(interface{ Template() string }); ok {
+		return t.Template()
+	}
+	return m.String()
+}
+
+// Sampler tracks the messages with each key, and decides which
+// messages to send according to its SamplingOptions. Samplers are
+// safe for concurrent use. Most users will use a Sampler through
+// MakeSampling or the Sampled method on grip.Logger.
+type Sampler struct {
+	opts SamplingOptions
+	now  func() time.Time
+
+	mu          sync.Mutex
+	window      time.Time
+	keys        map[string]*sampleState
+	suppressed  map[string]int
+	maxPriority level.Priority
+}
+
+type sampleState struct {
+	count  int
+	tokens float64
+	last   time.Time
+}
+
+// NewSampler constructs a Sampler, applying the defaults for any
+// unset options.
+func NewSampler(opts SamplingOptions) *Sampler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Rate > 0 && opts.Burst <= 0 {
+		opts.Burst = max(1, int(math.Ceil(opts.Rate)))
+	}
+	if opts.Key == nil {
+		opts.Key = SamplingKey
+	}
+
+	return &Sampler{
+		opts:       opts,
+		now:        time.Now,
+		keys:       map[string]*sampleState{},
+		suppressed: map[string]int{},
+	}
+}
+
+// Interval returns the sampler's interval.
+func (s *Sampler) Interval() time.Duration { return s.opts.Interval }
+
+// Allow reports if the message should be sent. Messages that are not
+// allowed are counted in the next summary.
+func (s *Sampler) Allow(m message.Composer) bool {
+	key := s.opts.Key(m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.roll(now)
+
+	st, ok := s.keys[key]
+	if !ok {
+		st = &sampleState{tokens: float64(s.opts.Burst), last: now}
+		s.keys[key] = st
+	}
+
+	if s.sample(st) && s.limit(st, now) {
+		return true
+	}
+
+	s.suppressed[key]++
+	s.maxPriority = max(s.maxPriority, m.Priority())
+	return false
+}
+
+func (s *Sampler) sample(st *sampleState) bool {
+	st.count++
+	switch {
+	case s.opts.First <= 0 && s.opts.Thereafter <= 0:
+		return true
+	case st.count <= s.opts.First:
+		return true
+	case s.opts.Thereafter <= 0:
+		return false
+	default:
+		return (st.count-s.opts.First-1)%s.opts.Thereafter == 0
+	}
+}
+
+func (s *Sampler) limit(st *sampleState, now time.Time) bool {
+	if s.opts.Rate <= 0 {
+		return true
+	}
+
+	st.tokens = min(float64(s.opts.Burst), st.tokens+now.Sub(st.last).Seconds()*s.opts.Rate)
+	st.last = now
+	if st.tokens < 1 {
+		return false
+	}
+	st.tokens--
+	return true
+}
+
+// roll resets the counters once the interval has elapsed, and
+// forgets the keys whose buckets are full, so that the sampler does
+// not retain keys that are no longer logged. roll must be called
+// with the lock held.
+func (s *Sampler) roll(now time.Time) {
+	if now.Sub(s.window) < s.opts.Interval {
+		return
+	}
+	s.window = now
+
+	for key, st := range s.keys {
+		if s.opts.Rate <= 0 || st.tokens+now.Sub(st.last).Seconds()*s.opts.Rate >= float64(s.opts.Burst) {
+			delete(s.keys, key)
+			continue
+		}
+		st.count = 0
+	}
+}
+
+// Summary returns a message that reports the number of messages that
+// were suppressed, by key, since the last summary, or nil if no
+// messages were suppressed.
+func (s *Sampler) Summary() message.Composer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.suppressed) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, count := range s.suppressed {
+		total += count
+	}
+
+	m := message.NewKV().
+		KV(message.FieldsMsgName, "suppressed sampled messages").
+		KV("suppressed", total).
+		KV("keys", maps.Clone(s.suppressed))
+
+	if s.opts.SummaryPriority != level.Invalid {
+		m.SetPriority(s.opts.SummaryPriority)
+	} else {
+		m.SetPriority(s.maxPriority)
+	}
+
+	clear(s.suppressed)
+	s.maxPriority = level.Invalid
+	return m
+}
+
+// Run passes a summary to the function at every interval, until the
+// context is canceled, and then passes a final summary. Intervals
+// without suppressed messages do not produce summaries.
+func (s *Sampler) Run(ctx context.Context, fn func(message.Composer)) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if m := s.Summary(); m != nil {
+				fn(m)
+			}
+			return
+		case <-ticker.C:
+			if m := s.Summary(); m != nil {
+				fn(m)
+			}
+		}
+	}
+}
+
+type samplingSender struct {
+	Sender
+	sampler *Sampler
+	cancel  context.CancelFunc
+	done    chan struct{}
+	close   sync.Once
+}
+
+// MakeSampling wraps a sender so that messages are sampled and rate
+// limited according to the options (see SamplingOptions.) Only
+// messages that pass the sender's threshold are counted. At every
+// interval, the sender sends a summary of the suppressed messages to
+// the underlying sender.
+//
+// The sampling sender owns the underlying sender: closing the
+// sampling sender sends a final summary and closes the underlying
+// sender.
+func MakeSampling(sender Sender, opts SamplingOptions) Sender {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &samplingSender{
+		Sender:  sender,
+		sampler: NewSampler(opts),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		s.sampler.Run(ctx, s.Sender.Send)
+	}()
+
+	return s
+}
+
+func (s *samplingSender) Unwrap() Sender { return s.Sender }
+
+func (s *samplingSender) Send(m message.Composer) {
+	if ShouldLog(s, m) && s.sampler.Allow(m) {
+		s.Sender.Send(m)
+	}
+}
+
+func (s *samplingSender) SendContext(ctx context.Context, m message.Composer) {
+	if ShouldLog(s, m) && s.sampler.Allow(m) {
+		SendContext(ctx, s.Sender, m)
+	}
+}
+
+func (s *samplingSender) Close() error {
+	s.close.Do(func() {
+		s.cancel()
+		<-s.done
+	})
+	return s.Sender.Close()
+}
@@ -0,0 +1,165 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func newTestSampler(opts SamplingOptions) (*Sampler, *testClock) {
+	s := NewSampler(opts)
+	return s, withTestClock(&s.now)
+}
+
+func countAllowed(s *Sampler, n int, m func(int) message.Composer) int {
+	allowed := 0
+	for i := range n {
+		if s.Allow(m(i)) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func formatted(i int) message.Composer {
+	m := message.MakeFormat("request %d", i)
+	m.SetPriority(level.Info)
+	return m
+}
+
+func TestSampler(t *testing.T) {
+	t.Run("Unconfigured", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{})
+		check.Equal(t, countAllowed(s, 100, formatted), 100)
+		check.True(t, s.Summary() == nil)
+	})
+	t.Run("FirstThenEvery", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingOptions{First: 3, Thereafter: 10, Interval: time.Second})
+		// 3 first, then the 4th, 14th, ..., 94th
+		check.Equal(t, countAllowed(s, 100, formatted), 3+10)
+
+		clock.Advance(time.Second)
+		check.Equal(t, countAllowed(s, 3, formatted), 3)
+	})
+	t.Run("FirstOnly", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{First: 5})
+		check.Equal(t, countAllowed(s, 100, formatted), 5)
+	})
+	t.Run("EveryOnly", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{Thereafter: 4})
+		check.Equal(t, countAllowed(s, 100, formatted), 25)
+	})
+	t.Run("PerKey", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{First: 1})
+		check.Equal(t, countAllowed(s, 10, formatted), 1)
+		check.Equal(t, countAllowed(s, 10, func(i int) message.Composer {
+			return NewString(level.Info, fmt.Sprint("distinct ", i))
+		}), 10)
+	})
+	t.Run("CustomKey", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{
+			First: 1,
+			Key:   func(m message.Composer) string { return m.Priority().String() },
+		})
+		check.Equal(t, countAllowed(s, 10, func(i int) message.Composer {
+			return NewString(level.Info, fmt.Sprint("distinct ", i))
+		}), 1)
+	})
+	t.Run("RateLimit", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingOptions{Rate: 2, Burst: 5})
+		check.Equal(t, countAllowed(s, 20, formatted), 5)
+
+		clock.Advance(time.Second)
+		check.Equal(t, countAllowed(s, 20, formatted), 2)
+
+		clock.Advance(time.Hour)
+		check.Equal(t, countAllowed(s, 20, formatted), 5)
+	})
+	t.Run("RateLimitAcrossIntervals", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingOptions{Rate: 1, Interval: time.Millisecond})
+		check.Equal(t, countAllowed(s, 5, formatted), 1)
+
+		// the empty bucket must survive the interval rolling over
+		clock.Advance(10 * time.Millisecond)
+		check.Equal(t, countAllowed(s, 5, formatted), 0)
+	})
+	t.Run("Summary", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{First: 1})
+		countAllowed(s, 5, formatted)
+		m := NewString(level.Error, "failure")
+		s.Allow(m)
+		s.Allow(m)
+
+		summary := s.Summary()
+		check.True(t, summary != nil)
+		check.Equal(t, summary.Priority(), level.Error)
+		check.Substring(t, summary.String(), "msg='suppressed sampled messages'")
+		check.Substring(t, summary.String(), "suppressed='5'")
+		check.Substring(t, summary.String(), "request %d:4")
+		check.Substring(t, summary.String(), "failure:1")
+
+		check.True(t, s.Summary() == nil)
+	})
+	t.Run("SummaryPriority", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingOptions{First: 1, SummaryPriority: level.Warning})
+		countAllowed(s, 5, formatted)
+		check.Equal(t, s.Summary().Priority(), level.Warning)
+	})
+	t.Run("Run", func(t *testing.T) {
+		s := NewSampler(SamplingOptions{First: 1, Interval: time.Millisecond})
+		countAllowed(s, 5, formatted)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		summaries := make(chan message.Composer, 10)
+		done := make(chan struct{})
+		go func() { defer close(done); s.Run(ctx, func(m message.Composer) { summaries <- m }) }()
+
+		select {
+		case m := <-summaries:
+			check.Substring(t, m.String(), "suppressed='4'")
+		case <-time.After(time.Second):
+			t.Fatal("no summary")
+		}
+
+		countAllowed(s, 5, formatted)
+		cancel()
+		<-done
+		check.True(t, len(summaries) >= 1)
+	})
+}
+
+func TestSamplingSender(t *testing.T) {
+	internal := MakeInternal()
+	s := MakeSampling(internal, SamplingOptions{First: 2, Interval: time.Hour})
+	s.SetPriority(level.Info)
+
+	for i := range 10 {
+		s.Send(formatted(i))
+		SendContext(t.Context(), s, formatted(i))
+		s.Send(NewString(level.Debug, "below threshold"))
+	}
+	check.Equal(t, internal.Len(), 2)
+
+	check.NotError(t, s.Close())
+	check.Equal(t, internal.Len(), 3)
+	for range 2 {
+		internal.GetMessage()
+	}
+	check.Substring(t, internal.GetMessage().Rendered, "suppressed='18'")
+
+	t.Run("Spec", func(t *testing.T) {
+		sender, err := BuildJSON([]byte(`{"type":"sampling","priority":"info","options":{"first":1,"interval":"1h"},"children":[{"type":"inmemory"}]}`))
+		check.NotError(t, err)
+		for range 5 {
+			sender.Send(NewString(level.Info, "hello"))
+		}
+		mem := sender.(*samplingSender).Sender.(*InMemorySender)
+		check.Equal(t, len(mem.Get()), 1)
+		check.NotError(t, sender.Close())
+	})
+}
@@ -210,6 +210,47 @@ func functionalMockSenders(t *testing.T, in map[string]Sender) map[string]Sender
 	return out
 }
 
+// testClock replaces the clock of senders that read the time from a
+// now field, so that tests control the passage of time.
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func withTestClock(now *func() time.Time) *testClock {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	*now = clock.Now
+	return clock
+}
+
+// newTestInternal returns an internal sender, at the Info level, for
+// wrapping senders to deliver to.
+func newTestInternal() *InternalSender {
+	internal := MakeInternal()
+	internal.SetPriority(level.Info)
+	return internal
+}
+
+// drainLogged returns the messages that the internal sender logged,
+// in order.
+func drainLogged(s *InternalSender) []*InternalMessage {
+	var out []*InternalMessage
+	for s.HasMessage() {
+		if msg := s.GetMessage(); msg.Logged {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+func renderedLogged(s *InternalSender) []string {
+	var out []string
+	for _, msg := range drainLogged(s) {
+		out = append(out, msg.Rendered)
+	}
+	return out
+}
+
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()"
 
 func randomString(n int, r *rand.Rand) string {
@@ -0,0 +1,538 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tychoish/fun/adt"
+	"github.com/tychoish/fun/erc"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// Spec is a declarative description of a tree of senders. Specs can
+// be decoded from JSON (see ParseSpec) or, because the fields carry
+// yaml tags, from YAML using any YAML library, and are assembled
+// into a Sender with Build.
+//
+// The Type selects the constructor registered with RegisterType;
+// Children are built first and passed to the constructor. Options
+// are specific to each type, and constructors read them with
+// DecodeOptions. The Name, Priority, and Formatter, when specified,
+// are applied to the sender after it is constructed.
+type Spec struct {
+	Type      string         `bson:"type" json:"type" yaml:"type"`
+	Name      string         `bson:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Priority  string         `bson:"priority,omitempty" json:"priority,omitempty" yaml:"priority,omitempty"`
+	Formatter string         `bson:"formatter,omitempty" json:"formatter,omitempty" yaml:"formatter,omitempty"`
+	Children  []Spec         `bson:"children,omitempty" json:"children,omitempty" yaml:"children,omitempty"`
+	Options   map[string]any `bson:"options,omitempty" json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// SpecFactory constructs a sender of a registered type from the spec
+// and its (already constructed) children. Factories that return an
+// error do not need to close the children.
+type SpecFactory func(spec Spec, children []Sender) (Sender, error)
+
+var (
+	specTypes      = &adt.SyncMap[string, SpecFactory]{}
+	specFormatters = &adt.SyncMap[string, func() MessageFormatter]{}
+)
+
+func init() {
+	RegisterType("stdout", leafFactory(MakeStdOutput))
+	RegisterType("stderr", leafFactory(MakeStdError))
+	RegisterType("nop", leafFactory(NopSender))
+	RegisterType("file", makeFileFromSpec)
+	RegisterType("inmemory", makeInMemoryFromSpec)
+	RegisterType("multi", func(_ Spec, children []Sender) (Sender, error) { return MakeMulti(children...), nil })
+	RegisterType("async", makeAsyncGroupFromSpec)
+	RegisterType("buffered", makeBufferedFromSpec)
+	RegisterType("annotating", makeAnnotatingFromSpec)
+	RegisterType("sampling", makeSamplingFromSpec)
+	RegisterType("deduplicating", makeDeduplicatingFromSpec)
+	RegisterType("router", makeRouterFromSpec)
+	RegisterType("masked", makeMaskedFromSpec)
+	RegisterType("escalating", makeEscalatingFromSpec)
+	RegisterType("recorder", makeRecorderFromSpec)
+	RegisterType("redacting", makeRedactingFromSpec)
+	RegisterType("console", makeConsoleFromSpec)
+	RegisterType("binary", makeBinaryFromSpec)
+
+	RegisterFormatter("default", MakeDefaultFormatter)
+	RegisterFormatter("plain", MakePlainFormatter)
+	RegisterFormatter("json", MakeJSONFormatter)
+	RegisterFormatter("logfmt", MakeLogfmtFormatter)
+	RegisterFormatter("envelope", func() MessageFormatter { return MakeEnvelopeFormatter(DefaultEnvelopeOptions()) })
+	RegisterFormatter("ecs", func() MessageFormatter { return MakeEnvelopeFormatter(ECSEnvelopeOptions()) })
+	RegisterFormatter("gcp", func() MessageFormatter { return MakeEnvelopeFormatter(GCPEnvelopeOptions()) })
+	RegisterFormatter("datadog", func() MessageFormatter { return MakeEnvelopeFormatter(DatadogEnvelopeOptions()) })
+}
+
+// RegisterType makes a sender type available to Build, replacing any
+// existing type with the same name. Packages in the x/ hierarchy
+// register their types during init.
+func RegisterType(name string, factory SpecFactory) { specTypes.Store(name, factory) }
+
+// RegisterFormatter makes a formatter available, by name, to the
+// Formatter field of a Spec.
+func RegisterFormatter(name string, constructor func() MessageFormatter) {
+	specFormatters.Store(name, constructor)
+}
+
+// ParseSpec decodes a JSON sender spec.
+func ParseSpec(data []byte) (Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return Spec{}, fmt.Errorf("parsing sender spec: %w", err)
+	}
+	return spec, nil
+}
+
+// BuildJSON parses and builds a JSON sender spec.
+func BuildJSON(data []byte) (Sender, error) {
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, err
+	}
+	return Build(spec)
+}
+
+// Build assembles the sender described by the spec. If any part of
+// the tree cannot be built, senders that were already constructed
+// are closed.
+//
+// Ownership of children follows the semantics of the underlying
+// constructor: multi, async, router, annotating, sampling,
+// deduplicating, masked, escalating, recorder, and redacting senders
+// close their children, while buffered senders do not.
+func Build(spec Spec) (Sender, error) {
+	factory, ok := specTypes.Load(spec.Type)
+	if !ok {
+		return nil, fmt.Errorf("sender type %q is not registered", spec.Type)
+	}
+
+	p := level.Invalid
+	if spec.Priority != "" {
+		if p = level.FromString(spec.Priority); p == level.Invalid {
+			return nil, fmt.Errorf("sender %q: %q is not a valid priority", spec.Type, spec.Priority)
+		}
+	}
+
+	var fmtr MessageFormatter
+	if spec.Formatter != "" {
+		constructor, ok := specFormatters.Load(spec.Formatter)
+		if !ok {
+			return nil, fmt.Errorf("sender %q: formatter %q is not registered", spec.Type, spec.Formatter)
+		}
+		fmtr = constructor()
+	}
+
+	children := make([]Sender, 0, len(spec.Children))
+	for idx := range spec.Children {
+		child, err := Build(spec.Children[idx])
+		if err != nil {
+			return nil, erc.Join(fmt.Errorf("sender %q child %d: %w", spec.Type, idx, err), closeAll(children))
+		}
+		children = append(children, child)
+	}
+
+	s, err := factory(spec, children)
+	if err != nil {
+		return nil, erc.Join(fmt.Errorf("sender %q: %w", spec.Type, err), closeAll(children))
+	}
+
+	if spec.Name != "" {
+		s.SetName(spec.Name)
+	}
+	if p != level.Invalid {
+		s.SetPriority(p)
+	}
+	if fmtr != nil {
+		s.SetFormatter(fmtr)
+	}
+
+	return s, nil
+}
+
+// DecodeOptions decodes the spec's options into the value pointed to
+// by out, using the JSON names of the fields. Durations may be
+// specified as strings parsed by time.ParseDuration, if the field
+// has the Duration type.
+func (spec Spec) DecodeOptions(out any) error {
+	if len(spec.Options) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(spec.Options)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("decoding options for %q: %w", spec.Type, err)
+	}
+	return nil
+}
+
+// Duration is a time.Duration that can be decoded from a string
+// (e.g. "10s") in a Spec's options.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(in []byte) error {
+	var str string
+	if err := json.Unmarshal(in, &str); err != nil {
+		var nanos int64
+		if err := json.Unmarshal(in, &nanos); err != nil {
+			return fmt.Errorf("%s is not a valid duration", in)
+		}
+		*d = Duration(nanos)
+		return nil
+	}
+
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+func closeAll(senders []Sender) error {
+	ec := &erc.Collector{}
+	for _, s := range senders {
+		ec.Push(s.Close())
+	}
+	return ec.Resolve()
+}
+
+func expectChildren(children []Sender, n int) error {
+	if len(children) != n {
+		return fmt.Errorf("requires %d child senders, not %d", n, len(children))
+	}
+	return nil
+}
+
+func leafFactory(constructor func() Sender) SpecFactory {
+	return func(_ Spec, children []Sender) (Sender, error) {
+		if err := expectChildren(children, 0); err != nil {
+			return nil, err
+		}
+		return constructor(), nil
+	}
+}
+
+func makeConsoleFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Output     string `json:"output"`
+		Color      string `json:"color"`
+		TimeLayout string `json:"time_layout"`
+	}
+	if err := erc.Join(expectChildren(children, 0), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+
+	var wr *os.File
+	switch opts.Output {
+	case "", "stdout":
+		wr = os.Stdout
+	case "stderr":
+		wr = os.Stderr
+	default:
+		return nil, fmt.Errorf("console output %q must be stdout or stderr", opts.Output)
+	}
+
+	conf := ConsoleOptions{TimeLayout: opts.TimeLayout}
+	switch opts.Color {
+	case "", "auto":
+		conf.Color = ColorEnabled(wr)
+	case "always":
+		conf.Color = true
+	case "never":
+	default:
+		return nil, fmt.Errorf("console color %q must be auto, always, or never", opts.Color)
+	}
+
+	s := MakeWriter(wr)
+	s.SetFormatter(MakeConsoleFormatter(conf))
+	return s, nil
+}
+
+func makeBinaryFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Path     string         `json:"path"`
+		Encoding BinaryEncoding `json:"encoding"`
+	}
+	if err := erc.Join(expectChildren(children, 0), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	if opts.Path == "" {
+		return nil, errors.New("binary senders require a path option")
+	}
+	switch opts.Encoding {
+	case "":
+		opts.Encoding = CBOR
+	case CBOR, MessagePack, BSON:
+	default:
+		return nil, fmt.Errorf("binary encoding %q is not supported", opts.Encoding)
+	}
+
+	f, err := os.OpenFile(opts.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("error opening logging file: %w", err)
+	}
+	s := MakeBinaryWriter(f, opts.Encoding)
+	s.(*binaryWriter).SetCloseHook(f.Close)
+	return s, nil
+}
+
+func makeFileFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Path string `json:"path"`
+	}
+	if err := erc.Join(expectChildren(children, 0), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	if opts.Path == "" {
+		return nil, errors.New("file senders require a path option")
+	}
+	return MakeFile(opts.Path)
+}
+
+func makeInMemoryFromSpec(spec Spec, children []Sender) (Sender, error) {
+	opts := struct {
+		Capacity int `json:"capacity"`
+	}{Capacity: 1000}
+	if err := erc.Join(expectChildren(children, 0), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return NewInMemorySender(spec.Name, level.FromString(spec.Priority), opts.Capacity)
+}
+
+func makeAsyncGroupFromSpec(spec Spec, children []Sender) (Sender, error) {
+	opts := struct {
+		BufferSize int `json:"buffer_size"`
+	}{BufferSize: 100}
+	if err := spec.DecodeOptions(&opts); err != nil {
+		return nil, err
+	}
+	return MakeAsyncGroup(context.Background(), opts.BufferSize, children...), nil
+}
+
+func makeBufferedFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Interval Duration `json:"interval"`
+		Size     int      `json:"size"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeBuffered(children[0], time.Duration(opts.Interval), opts.Size), nil
+}
+
+func makeAnnotatingFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Annotations map[string]any `json:"annotations"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeAnnotating(children[0], opts.Annotations), nil
+}
+
+func makeSamplingFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		First      int      `json:"first"`
+		Thereafter int      `json:"thereafter"`
+		Rate       float64  `json:"rate"`
+		Burst      int      `json:"burst"`
+		Interval   Duration `json:"interval"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeSampling(children[0], SamplingOptions{
+		First:      opts.First,
+		Thereafter: opts.Thereafter,
+		Rate:       opts.Rate,
+		Burst:      opts.Burst,
+		Interval:   time.Duration(opts.Interval),
+	}), nil
+}
+
+func makeDeduplicatingFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Window Duration `json:"window"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeDeduplicating(children[0], time.Duration(opts.Window)), nil
+}
+
+// makeRouterFromSpec builds a router with one child for each of the
+// routes (which specify the min and max priorities,) followed by the
+// default destination when the default option is set.
+func makeRouterFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Routes []struct {
+			Min string `json:"min"`
+			Max string `json:"max"`
+		} `json:"routes"`
+		Default bool `json:"default"`
+	}
+	if err := spec.DecodeOptions(&opts); err != nil {
+		return nil, err
+	}
+
+	n := len(opts.Routes)
+	if opts.Default {
+		n++
+	}
+	if err := expectChildren(children, n); err != nil {
+		return nil, err
+	}
+
+	routes := make([]Route, 0, len(opts.Routes))
+	for idx, r := range opts.Routes {
+		route := Route{Sender: children[idx]}
+		for _, bound := range []struct {
+			value string
+			out   *level.Priority
+		}{{r.Min, &route.Min}, {r.Max, &route.Max}} {
+			if bound.value == "" {
+				continue
+			}
+			if *bound.out = level.FromString(bound.value); *bound.out == level.Invalid {
+				return nil, fmt.Errorf("route %d: %q is not a valid priority", idx, bound.value)
+			}
+		}
+		routes = append(routes, route)
+	}
+
+	var def Sender
+	if opts.Default {
+		def = children[len(children)-1]
+	}
+	return MakeRouter(def, routes...), nil
+}
+
+// makeMaskedFromSpec builds a masked sender from either a list of
+// levels or a range, specified with min and/or max.
+func makeMaskedFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Levels []string `json:"levels"`
+		Min    string   `json:"min"`
+		Max    string   `json:"max"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(opts.Levels) > 0 && (opts.Min != "" || opts.Max != ""):
+		return nil, errors.New("masked senders take either levels or a range, not both")
+	case len(opts.Levels) > 0:
+		mask, err := level.ParseMask(strings.Join(opts.Levels, ","))
+		if err != nil {
+			return nil, err
+		}
+		return MakeMasked(children[0], mask), nil
+	case opts.Min != "" || opts.Max != "":
+		var r level.Range
+		for _, bound := range []struct {
+			value string
+			out   *level.Priority
+		}{{opts.Min, &r.Min}, {opts.Max, &r.Max}} {
+			if bound.value == "" {
+				continue
+			}
+			p, err := level.Parse(bound.value)
+			if err != nil {
+				return nil, err
+			}
+			*bound.out = p
+		}
+		return MakeMasked(children[0], r), nil
+	default:
+		return nil, errors.New("masked senders require levels or a range")
+	}
+}
+
+func makeEscalatingFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Threshold int            `json:"threshold"`
+		Window    Duration       `json:"window"`
+		Duration  Duration       `json:"duration"`
+		Trigger   level.Priority `json:"trigger"`
+		Level     level.Priority `json:"level"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeEscalating(children[0], EscalationOptions{
+		Threshold: opts.Threshold,
+		Window:    time.Duration(opts.Window),
+		Duration:  time.Duration(opts.Duration),
+		Trigger:   opts.Trigger,
+		Level:     opts.Level,
+	}), nil
+}
+
+func makeRecorderFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Size      int            `json:"size"`
+		Age       Duration       `json:"age"`
+		Threshold level.Priority `json:"threshold"`
+		Trigger   level.Priority `json:"trigger"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+	return MakeFlightRecorder(children[0], RecorderOptions{
+		Size:      opts.Size,
+		Age:       time.Duration(opts.Age),
+		Threshold: opts.Threshold,
+		Trigger:   opts.Trigger,
+	}), nil
+}
+
+// makeRedactingFromSpec builds a redacting sender. When the defaults
+// option is set, the keys and values extend message.DefaultRedactor.
+func makeRedactingFromSpec(spec Spec, children []Sender) (Sender, error) {
+	var opts struct {
+		Defaults bool     `json:"defaults"`
+		Keys     []string `json:"keys"`
+		Values   []string `json:"values"`
+		Mask     string   `json:"mask"`
+	}
+	if err := erc.Join(expectChildren(children, 1), spec.DecodeOptions(&opts)); err != nil {
+		return nil, err
+	}
+
+	redactor := &message.Redactor{}
+	if opts.Defaults {
+		redactor = message.DefaultRedactor()
+	}
+	redactor.Keys = append(redactor.Keys, opts.Keys...)
+	redactor.Mask = opts.Mask
+	for _, expr := range opts.Values {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", expr, err)
+		}
+		redactor.Values = append(redactor.Values, pattern)
+	}
+
+	return MakeRedacting(children[0], redactor), nil
+}
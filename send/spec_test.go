@@ -0,0 +1,113 @@
+package send
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestSpec(t *testing.T) {
+	t.Run("Tree", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tree.log")
+		s, err := BuildJSON([]byte(`{
+			"type": "multi",
+			"name": "tree",
+			"children": [
+				{"type": "stderr", "priority": "error", "formatter": "json"},
+				{"type": "file", "options": {"path": "` + path + `"}},
+				{"type": "buffered", "options": {"interval": "10s", "size": 10}, "children": [{"type": "nop"}]}
+			]
+		}`))
+		check.NotError(t, err)
+		check.Equal(t, s.Name(), "tree")
+
+		ms, ok := s.(*multiSender)
+		check.True(t, ok)
+		check.Equal(t, len(ms.senders), 3)
+		check.Equal(t, ms.senders[0].Priority(), level.Error)
+		check.Equal(t, ms.senders[0].Name(), "tree")
+		out, err := ms.senders[0].GetFormatter()(message.MakeString("hi"))
+		check.NotError(t, err)
+		check.True(t, strings.HasPrefix(out, "{"))
+		check.NotError(t, s.Close())
+	})
+	t.Run("Annotating", func(t *testing.T) {
+		s, err := Build(Spec{
+			Type:     "annotating",
+			Priority: "debug",
+			Options:  map[string]any{"annotations": map[string]any{"service": "api"}},
+			Children: []Spec{{Type: "inmemory", Options: map[string]any{"capacity": 2}}},
+		})
+		check.NotError(t, err)
+		check.Equal(t, s.Priority(), level.Debug)
+
+		s.Send(message.NewKV().KV("msg", "hello").Level(level.Info))
+		mem := s.(interface{ Unwrap() Sender }).Unwrap().(*InMemorySender)
+		msgs, err := mem.GetString()
+		check.NotError(t, err)
+		check.Equal(t, len(msgs), 1)
+		check.Substring(t, msgs[0], "service='api'")
+	})
+	t.Run("Async", func(t *testing.T) {
+		s, err := Build(Spec{Type: "async", Options: map[string]any{"buffer_size": 4}, Children: []Spec{{Type: "nop"}}})
+		check.NotError(t, err)
+		check.NotError(t, s.Close())
+	})
+	t.Run("Errors", func(t *testing.T) {
+		for name, spec := range map[string]Spec{
+			"UnknownType":      {Type: "carrier-pigeon"},
+			"BadPriority":      {Type: "stdout", Priority: "loud"},
+			"BadFormatter":     {Type: "stdout", Formatter: "xml"},
+			"LeafChildren":     {Type: "stdout", Children: []Spec{{Type: "nop"}}},
+			"MissingChild":     {Type: "buffered"},
+			"FilePath":         {Type: "file"},
+			"UnknownOption":    {Type: "file", Options: map[string]any{"pth": "x"}},
+			"BadDuration":      {Type: "buffered", Options: map[string]any{"interval": "soon"}, Children: []Spec{{Type: "nop"}}},
+			"BadChild":         {Type: "multi", Children: []Spec{{Type: "nop"}, {Type: "nope"}}},
+			"InMemoryCapacity": {Type: "inmemory", Options: map[string]any{"capacity": 0}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				s, err := Build(spec)
+				check.Error(t, err)
+				check.True(t, s == nil)
+			})
+		}
+		_, err := BuildJSON([]byte(`{"type": `))
+		check.Error(t, err)
+	})
+	t.Run("ChildrenClosedOnError", func(t *testing.T) {
+		var closed int
+		RegisterType("test-closer", func(Spec, []Sender) (Sender, error) {
+			s := NopSender()
+			s.(*noopSender).SetCloseHook(func() error { closed++; return nil })
+			return s, nil
+		})
+		expected := errors.New("failed")
+		RegisterType("test-failer", func(Spec, []Sender) (Sender, error) { return nil, expected })
+
+		_, err := Build(Spec{Type: "test-failer", Children: []Spec{{Type: "test-closer"}, {Type: "test-closer"}}})
+		check.ErrorIs(t, err, expected)
+		check.Equal(t, closed, 2)
+
+		_, err = Build(Spec{Type: "multi", Children: []Spec{{Type: "test-closer"}, {Type: "test-failer"}}})
+		check.ErrorIs(t, err, expected)
+		check.Equal(t, closed, 3)
+	})
+	t.Run("Duration", func(t *testing.T) {
+		var opts struct {
+			A Duration `json:"a"`
+			B Duration `json:"b"`
+		}
+		spec := Spec{Options: map[string]any{"a": "1m", "b": 5}}
+		check.NotError(t, spec.DecodeOptions(&opts))
+		check.Equal(t, time.Duration(opts.A), time.Minute)
+		check.Equal(t, time.Duration(opts.B), 5*time.Nanosecond)
+		check.Error(t, Spec{Options: map[string]any{"a": true}}.DecodeOptions(&opts))
+	})
+}
@@ -0,0 +1,399 @@
+package send
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+// DefaultTemplateCallSiteDepth is the depth of the call site used by
+// the {caller} directive of template formatters when the directive
+// does not specify a depth. As with grip.DefaultCallSiteDepth, it is
+// correct for messages logged using the methods on grip.Logger with
+// senders that format messages when they are sent.
+const DefaultTemplateCallSiteDepth = 5
+
+// ANSI escape sequences for the colors supported by template
+// formatters.
+var ansiColors = map[string]string{
+	"black":   "\x1b[30m",
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+	"white":   "\x1b[37m",
+	"gray":    "\x1b[90m",
+	"bold":    "\x1b[1m",
+	"dim":     "\x1b[2m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// levelColor returns the ANSI escape sequence for the color
+// associated with the priority.
+func levelColor(p level.Priority) string {
+	switch {
+	case p >= level.Critical:
+		return "\x1b[1;31m"
+	case p >= level.Error:
+		return ansiColors["red"]
+	case p >= level.Warning:
+		return ansiColors["yellow"]
+	case p >= level.Notice:
+		return ansiColors["cyan"]
+	case p >= level.Info:
+		return ansiColors["green"]
+	default:
+		return ansiColors["gray"]
+	}
+}
+
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc822":      time.RFC822,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+	"stampmilli":  time.StampMilli,
+	"stampmicro":  time.StampMicro,
+	"datetime":    time.DateTime,
+	"dateonly":    time.DateOnly,
+	"timeonly":    time.TimeOnly,
+}
+
+// templateMessage holds a message while it's formatted, so that
+// directives can share the message's fields.
+type templateMessage struct {
+	msg      message.Composer
+	text     string
+	fields   []fieldPair
+	resolved bool
+}
+
+type fieldPair struct {
+	key   string
+	value any
+}
+
+func (tm *templateMessage) resolve() {
+	if tm.resolved {
+		return
+	}
+	text, fields := logfmtFields(tm.msg)
+	tm.text = text
+	for key, value := range fields {
+		tm.fields = append(tm.fields, fieldPair{key: key, value: value})
+	}
+	tm.resolved = true
+}
+
+type templateSegment func(*strings.Builder, *templateMessage)
+
+// MakeTemplateFormatter compiles a format string into a
+// MessageFormatter. Format strings contain literal text and
+// directives in braces, as in:
+//
+//	{time:rfc3339} {level:upper:-7} {host} [{caller}] {msg} {fields}
+//
+// The directives are:
+//
+//	{msg}             the message (for KV and Fields messages, the "msg" field)
+//	{fields}          the message's other fields, as logfmt pairs
+//	{fields:a,b}      the named fields, as logfmt pairs
+//	{field:name}      the value of the named field
+//	{level}           the priority of the message (also {priority})
+//	{time}            the message timestamp (see message.Timestamp), in RFC 3339 format
+//	{caller}          the file and line of the call site
+//	{host}, {pid}, {proc}
+//	                  the host name, process id, and process name
+//
+// Arguments follow the directive name, separated by colons. The time
+// directive accepts a layout name (rfc3339, rfc3339nano, rfc1123,
+// rfc822, kitchen, stamp, stampmilli, stampmicro, datetime, dateonly,
+// timeonly, unix, unixmilli) or a Go layout in single quotes (e.g.
+// {time:'15:04:05.000'}.) The level directive accepts "num" to render
+// the numeric priority. The caller directive accepts "depth=N", with
+// the same meaning as the argument to MakeCallSiteFormatter, and
+// defaults to DefaultTemplateCallSiteDepth.
+//
+// All directives accept the following modifiers: "upper" and
+// "lower" change the case of the value; an integer pads the value
+// to that width, aligned to the right, or to the left when the
+// integer is negative (as with fmt); and a color name (black, red,
+// green, yellow, blue, magenta, cyan, white, gray, bold, or dim) or
+// "color", which picks a color based on the priority of the message,
+// wraps the value in ANSI color escape sequences.
+//
+// Use "{{" and "}}" for literal braces. Trailing spaces are removed
+// from the output. MakeTemplateFormatter returns an error if the
+// format string is not valid. The formatter itself never errors.
+func MakeTemplateFormatter(format string) (MessageFormatter, error) {
+	var segments []templateSegment
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			text := literal.String()
+			segments = append(segments, func(buf *strings.Builder, _ *templateMessage) { buf.WriteString(text) })
+			literal.Reset()
+		}
+	}
+
+	for idx := 0; idx < len(format); idx++ {
+		switch ch := format[idx]; {
+		case strings.HasPrefix(format[idx:], "{{"), strings.HasPrefix(format[idx:], "}}"):
+			literal.WriteByte(ch)
+			idx++
+		case ch == '}':
+			return nil, fmt.Errorf("template: unexpected '}' at offset %d", idx)
+		case ch == '{':
+			end := strings.IndexByte(format[idx:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("template: unterminated directive at offset %d", idx)
+			}
+			seg, err := compileDirective(format[idx+1 : idx+end])
+			if err != nil {
+				return nil, fmt.Errorf("template: %w", err)
+			}
+			flush()
+			segments = append(segments, seg)
+			idx += end
+		default:
+			literal.WriteByte(ch)
+		}
+	}
+	flush()
+
+	return func(m message.Composer) (string, error) {
+		var buf strings.Builder
+		tm := &templateMessage{msg: m}
+		for _, seg := range segments {
+			seg(&buf, tm)
+		}
+		// directives that render empty values (e.g. {fields})
+		// should not leave trailing space.
+		return strings.TrimRight(buf.String(), " "), nil
+	}, nil
+}
+
+// splitDirective splits the directive on colons, except for colons
+// within single quotes.
+func splitDirective(in string) ([]string, error) {
+	var (
+		out     []string
+		current strings.Builder
+		quoted  bool
+	)
+	for _, r := range in {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ':' && !quoted:
+			out = append(out, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in {%s}", in)
+	}
+	return append(out, current.String()), nil
+}
+
+func compileDirective(directive string) (templateSegment, error) {
+	parts, err := splitDirective(directive)
+	if err != nil {
+		return nil, err
+	}
+
+	name, args := strings.TrimSpace(parts[0]), parts[1:]
+
+	var value func(*templateMessage) string
+	switch name {
+	case "msg", "message":
+		value = func(tm *templateMessage) string { tm.resolve(); return tm.text }
+	case "fields":
+		var selected []string
+		if len(args) > 0 && !isTemplateModifier(args[0]) {
+			selected = strings.Split(args[0], ",")
+			args = args[1:]
+		}
+		value = func(tm *templateMessage) string {
+			tm.resolve()
+			var buf strings.Builder
+			for _, pair := range tm.fields {
+				if selected == nil || slices.Contains(selected, pair.key) {
+					writeLogfmtPair(&buf, pair.key, logfmtValue(pair.value))
+				}
+			}
+			return buf.String()
+		}
+	case "field":
+		if len(args) == 0 || args[0] == "" {
+			return nil, fmt.Errorf("{%s} requires a field name", directive)
+		}
+		key := args[0]
+		args = args[1:]
+		value = func(tm *templateMessage) string {
+			tm.resolve()
+			if key == message.FieldsMsgName {
+				return tm.text
+			}
+			for _, pair := range tm.fields {
+				if pair.key == key {
+					return logfmtValue(pair.value)
+				}
+			}
+			return ""
+		}
+	case "level", "priority":
+		numeric := len(args) > 0 && args[0] == "num"
+		if numeric {
+			args = args[1:]
+		}
+		value = func(tm *templateMessage) string {
+			if numeric {
+				return strconv.Itoa(int(tm.msg.Priority()))
+			}
+			return tm.msg.Priority().String()
+		}
+	case "time":
+		layout := time.RFC3339
+		if len(args) > 0 && !isTemplateModifier(args[0]) {
+			switch arg := args[0]; {
+			case arg == "unix":
+				layout = arg
+			case arg == "unixmilli":
+				layout = arg
+			case strings.HasPrefix(arg, "'"):
+				layout = strings.Trim(arg, "'")
+			default:
+				var ok bool
+				if layout, ok = timeLayouts[arg]; !ok {
+					return nil, fmt.Errorf("%q is not a time layout", arg)
+				}
+			}
+			args = args[1:]
+		}
+		value = func(tm *templateMessage) string {
+			ts := message.Timestamp(tm.msg)
+			switch layout {
+			case "unix":
+				return strconv.FormatInt(ts.Unix(), 10)
+			case "unixmilli":
+				return strconv.FormatInt(ts.UnixMilli(), 10)
+			default:
+				return ts.Format(layout)
+			}
+		}
+	case "caller":
+		depth := DefaultTemplateCallSiteDepth
+		if len(args) > 0 && strings.HasPrefix(args[0], "depth=") {
+			d, err := strconv.Atoi(strings.TrimPrefix(args[0], "depth="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid call site depth in {%s}", directive)
+			}
+			depth = d
+			args = args[1:]
+		}
+		// skip the value function, the segment, and the
+		// formatter, as MakeCallSiteFormatter skips its
+		// formatter (callerInfo skips itself.)
+		depth += 3
+		value = func(*templateMessage) string {
+			file, line := callerInfo(depth)
+			return fmt.Sprintf("%s:%d", file, line)
+		}
+	case "host":
+		host, _ := os.Hostname()
+		value = func(*templateMessage) string { return host }
+	case "pid":
+		pid := strconv.Itoa(os.Getpid())
+		value = func(*templateMessage) string { return pid }
+	case "proc":
+		proc := filepath.Base(os.Args[0])
+		value = func(*templateMessage) string { return proc }
+	default:
+		return nil, fmt.Errorf("unknown directive {%s}", directive)
+	}
+
+	return applyTemplateModifiers(directive, args, value)
+}
+
+func isTemplateModifier(arg string) bool {
+	if _, err := strconv.Atoi(arg); err == nil {
+		return true
+	}
+	_, isColor := ansiColors[arg]
+	return isColor || arg == "upper" || arg == "lower" || arg == "color"
+}
+
+// applyTemplateModifiers produces the segment for the value. The
+// segment must call the value function directly, as call site
+// directives count the frames between the value and the formatter.
+func applyTemplateModifiers(directive string, args []string, value func(*templateMessage) string) (templateSegment, error) {
+	var (
+		transform func(string) string
+		width     int
+		color     string
+		byLevel   bool
+	)
+	for _, arg := range args {
+		switch {
+		case arg == "upper":
+			transform = strings.ToUpper
+		case arg == "lower":
+			transform = strings.ToLower
+		case arg == "color":
+			byLevel = true
+		case ansiColors[arg] != "":
+			color = ansiColors[arg]
+		default:
+			w, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("unknown modifier %q in {%s}", arg, directive)
+			}
+			width = w
+		}
+	}
+
+	render := func(buf *strings.Builder, tm *templateMessage, out string) {
+		if transform != nil {
+			out = transform(out)
+		}
+		if pad := max(width, -width) - utf8.RuneCountInString(out); pad > 0 {
+			if width > 0 {
+				out = strings.Repeat(" ", pad) + out
+			} else {
+				out += strings.Repeat(" ", pad)
+			}
+		}
+
+		switch {
+		case byLevel:
+			buf.WriteString(levelColor(tm.msg.Priority()))
+		case color != "":
+			buf.WriteString(color)
+		default:
+			buf.WriteString(out)
+			return
+		}
+		buf.WriteString(out)
+		buf.WriteString(ansiReset)
+	}
+
+	return func(buf *strings.Builder, tm *templateMessage) { render(buf, tm, value(tm)) }, nil
+}
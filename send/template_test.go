@@ -0,0 +1,96 @@
+package send
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	render := func(t *testing.T, format string, m message.Composer) string {
+		t.Helper()
+		fmtr, err := MakeTemplateFormatter(format)
+		check.NotError(t, err)
+		out, err := fmtr(m)
+		check.NotError(t, err)
+		return out
+	}
+
+	kv := message.NewKV().KV("msg", "request complete").KV("path", "/api").KV("status", 200).KV("user", "jo smith")
+	kv.SetPriority(level.Warning)
+
+	t.Run("Fields", func(t *testing.T) {
+		check.Equal(t, render(t, "{level:upper:-7}| {msg} {fields}", kv),
+			`WARNING| request complete path=/api status=200 user="jo smith"`)
+		check.Equal(t, render(t, "{msg} [{fields:user,status}] {field:path} {field:msg} {field:missing}", kv),
+			`request complete [status=200 user="jo smith"] /api request complete`)
+		check.Equal(t, render(t, "[{level:8}] [{level:num}]", kv), "[ warning] [150]")
+	})
+	t.Run("Unstructured", func(t *testing.T) {
+		check.Equal(t, render(t, "{{{level}}} {msg} {fields}", NewString(level.Info, "hello")), "{info} hello")
+
+		annotated := NewString(level.Info, "c")
+		annotated.Annotate("k", "v")
+		check.Equal(t, render(t, "{msg} {fields} {field:k}", annotated), "c k=v v")
+	})
+	t.Run("Colors", func(t *testing.T) {
+		check.Equal(t, render(t, "{level:color:upper}|{msg:blue}", kv),
+			"\x1b[33mWARNING\x1b[0m|\x1b[34mrequest complete\x1b[0m")
+		check.Equal(t, render(t, "{level:color:-6}|", NewString(level.Info, "x")), "\x1b[32minfo  \x1b[0m|")
+	})
+	t.Run("Time", func(t *testing.T) {
+		before := time.Now().Truncate(time.Second)
+		out := render(t, "{time}", kv)
+		ts, err := time.Parse(time.RFC3339, out)
+		check.NotError(t, err)
+		check.True(t, !ts.Before(before))
+
+		check.True(t, regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3}$`).MatchString(render(t, "{time:'15:04:05.000'}", kv)))
+		check.True(t, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`).MatchString(render(t, "{time:dateonly}", kv)))
+		check.True(t, regexp.MustCompile(`^\d{10}$`).MatchString(render(t, "{time:unix}", kv)))
+
+		// messages that record their time render it, rather
+		// than the time of formatting.
+		collected := NewString(level.Info, "collected")
+		collected.SetOption(message.OptionCollectInfo)
+		ts = message.Timestamp(collected)
+		time.Sleep(time.Millisecond)
+		check.Equal(t, render(t, "{time:rfc3339nano}", collected), ts.Format(time.RFC3339Nano))
+	})
+	t.Run("Process", func(t *testing.T) {
+		host, _ := os.Hostname()
+		check.Equal(t, render(t, "{host} {pid} {proc}", kv),
+			fmt.Sprintf("%s %d %s", host, os.Getpid(), filepath.Base(os.Args[0])))
+	})
+	t.Run("Caller", func(t *testing.T) {
+		fmtr, err := MakeTemplateFormatter("[{caller:depth=0}] {msg}")
+		check.NotError(t, err)
+		out, err := fmtr(kv)
+		_, _, line, _ := runtime.Caller(0)
+		check.NotError(t, err)
+		check.Equal(t, out, fmt.Sprintf("[send/template_test.go:%d] request complete", line-1))
+	})
+	t.Run("Errors", func(t *testing.T) {
+		for _, format := range []string{
+			"{msg", "msg}", "{unknown}", "{msg:sparkly}", "{time:century}",
+			"{time:'15:04}", "{field}", "{caller:depth=x}",
+		} {
+			_, err := MakeTemplateFormatter(format)
+			check.Error(t, err)
+		}
+	})
+	t.Run("Empty", func(t *testing.T) {
+		check.Equal(t, render(t, "", kv), "")
+		check.Equal(t, render(t, "literal", kv), "literal")
+		check.True(t, !strings.Contains(render(t, "{fields}", kv), "msg="))
+	})
+}
@@ -51,18 +51,55 @@ func MakeWriterSender(s Sender) WriterSender {
 	return &writerSenderImpl{
 		Sender: s,
 
-		writer: bufio.NewWriter(buffer),
-		buffer: buffer,
+		writer:  bufio.NewWriter(buffer),
+		buffer:  buffer,
+		compose: composeBytes,
 	}
 }
 
+// MakeLogfmtWriterSender is the same as MakeWriterSender, except
+// that it parses each line as logfmt (see message.ParseLogfmt,) so
+// that the keys become the fields of the messages, and the "level"
+// key, when present, sets the priority of the message. Lines that
+// are not valid logfmt, or that have no key=value pairs (e.g.
+// prose,) are sent as they would be by MakeWriterSender.
+func MakeLogfmtWriterSender(s Sender) WriterSender {
+	out := MakeWriterSender(s).(*writerSenderImpl)
+	out.compose = composeLogfmt
+	return out
+}
+
+func composeBytes(line []byte, pri level.Priority) message.Composer {
+	m := message.MakeBytes(line)
+	m.SetPriority(pri)
+	return m
+}
+
+func composeLogfmt(line []byte, pri level.Priority) message.Composer {
+	// lines without pairs are prose, which ParseLogfmt would
+	// wrap in a "msg" field: keep them as they are, like lines
+	// that do not parse.
+	if !bytes.ContainsRune(line, '=') {
+		return composeBytes(line, pri)
+	}
+	m, err := message.ParseLogfmt(string(line))
+	if err != nil {
+		return composeBytes(line, pri)
+	}
+	if m.Priority() == level.Invalid {
+		m.SetPriority(pri)
+	}
+	return m
+}
+
 type writerSenderImpl struct {
 	Sender
 	adt.Atomic[level.Priority]
 
-	writer *bufio.Writer
-	buffer *bytes.Buffer
-	mu     sync.Mutex
+	writer  *bufio.Writer
+	buffer  *bytes.Buffer
+	compose func([]byte, level.Priority) message.Composer
+	mu      sync.Mutex
 }
 
 func (s *writerSenderImpl) Unwrap() Sender { return s.Sender }
@@ -90,15 +127,11 @@ func (s *writerSenderImpl) doSend() error {
 		}
 
 		if err == nil {
-			m := message.MakeBytes(bytes.TrimSpace(line))
-			m.SetPriority(pri)
-			s.Send(m)
+			s.Send(s.compose(bytes.TrimSpace(line), pri))
 			continue
 		}
 
-		m := message.MakeBytes(bytes.TrimSpace(line))
-		m.SetPriority(pri)
-		s.Send(m)
+		s.Send(s.compose(bytes.TrimSpace(line), pri))
 		s.buffer.Reset()
 		return err
 	}
@@ -114,9 +147,7 @@ func (s *writerSenderImpl) Close() error {
 		return err
 	}
 
-	m := message.MakeBytes(bytes.TrimRightFunc(s.buffer.Bytes(), unicode.IsSpace))
-	m.SetPriority(s.Load())
-	s.Send(m)
+	s.Send(s.compose(bytes.TrimRightFunc(s.buffer.Bytes(), unicode.IsSpace), s.Load()))
 	s.buffer.Reset()
 	s.writer.Reset(s.buffer)
 	return nil
@@ -0,0 +1,125 @@
+package grip
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/tychoish/fun/erc"
+	"github.com/tychoish/grip/send"
+)
+
+// inflight tracks the sends that are in progress for a sender, so
+// that a replaced sender can be closed once they complete. No lock is
+// held while sending: loggers increment the count before sending and
+// decrement it after, and SwapSender retires the sender and waits for
+// the count to reach zero, so senders (and their error handlers) may
+// log through grip while a swap is in progress.
+//
+// The count and the retired flag share a word, which sends update
+// atomically; only retiring senders wait on the channel.
+type inflight struct {
+	state   atomic.Int64
+	drained chan struct{}
+}
+
+const inflightRetired = int64(1) << 62
+
+func newInflight() *inflight { return &inflight{drained: make(chan struct{})} }
+
+// enter registers a send, unless the sender is retired. Once the
+// sender is retired the count never increases, so it reaches zero
+// only once.
+func (f *inflight) enter() bool {
+	for {
+		state := f.state.Load()
+		if state&inflightRetired != 0 {
+			return false
+		}
+		if f.state.CompareAndSwap(state, state+1) {
+			return true
+		}
+	}
+}
+
+func (f *inflight) exit() {
+	if f.state.Add(-1) == inflightRetired {
+		close(f.drained)
+	}
+}
+
+// retire prevents new sends and waits for the in-flight sends to
+// complete or for the context to end.
+func (f *inflight) retire(ctx context.Context) error {
+	if f.state.Or(inflightRetired) == 0 {
+		close(f.drained)
+	}
+
+	select {
+	case <-f.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire returns the logger's current sender, which the caller must
+// release. Senders are only retired after they have been replaced,
+// so if the sender is retired between loading and entering it, the
+// next load returns its replacement.
+func (g Logger) acquire() sender {
+	for {
+		if s := g.impl.Get(); s.enter() {
+			return s
+		}
+	}
+}
+
+func (s sender) release() { s.exit() }
+
+// ReplaceSender sets the logger's sender, like SetSender, and
+// returns the previous sender. Messages that are being sent
+// concurrently may still be delivered to the previous sender, so
+// callers that want to close the previous sender should use
+// SwapSender.
+func (g Logger) ReplaceSender(s send.Sender) send.Sender {
+	prev := g.impl.Swap(makeSender(s)).Sender
+	g.sharedSenderChanged()
+	return prev
+}
+
+// SwapSender replaces the logger's sender, and then flushes and
+// closes the previous sender after all in-flight sends to it have
+// completed. Sends that begin after SwapSender is called use the new
+// sender, and no message is sent to the previous sender after it is
+// closed. The context bounds both the wait for in-flight sends and
+// the flush of the previous sender: if the context ends before the
+// in-flight sends complete, SwapSender returns the context's error
+// and does not close the previous sender.
+//
+// The previous sender is not closed if it's the same as the new
+// sender. Because named loggers share the sender of the standard
+// logger, swapping the standard logger's sender affects them too;
+// loggers produced by Clone have their own sender and are not
+// affected.
+func (g Logger) SwapSender(ctx context.Context, s send.Sender) error {
+	prev := g.impl.Swap(makeSender(s))
+	g.sharedSenderChanged()
+
+	if err := prev.retire(ctx); err != nil {
+		return fmt.Errorf("waiting for in-flight sends to the previous sender: %w", err)
+	}
+	if prev.Sender == nil || prev.Sender == s {
+		return nil
+	}
+
+	return erc.Join(prev.Flush(ctx), prev.Close())
+}
+
+// ReplaceSender sets the standard logger's sender and returns the
+// previous sender; see Logger.ReplaceSender.
+func ReplaceSender(s send.Sender) send.Sender { return std.ReplaceSender(s) }
+
+// SwapSender replaces the standard logger's sender, and then flushes
+// and closes the previous sender; see Logger.SwapSender.
+func SwapSender(ctx context.Context, s send.Sender) error { return std.SwapSender(ctx, s) }
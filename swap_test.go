@@ -0,0 +1,207 @@
+package grip
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tychoish/fun/assert/check"
+	"github.com/tychoish/grip/level"
+	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
+)
+
+type closeTrackingSender struct {
+	send.Base
+	sent    atomic.Int64
+	late    atomic.Int64
+	flushed atomic.Bool
+	closed  atomic.Bool
+}
+
+func (s *closeTrackingSender) Send(m message.Composer) {
+	if !send.ShouldLog(s, m) {
+		return
+	}
+	if s.closed.Load() {
+		s.late.Add(1)
+	}
+	s.sent.Add(1)
+}
+
+func (s *closeTrackingSender) Flush(context.Context) error { s.flushed.Store(true); return nil }
+func (s *closeTrackingSender) Close() error                { s.closed.Store(true); return nil }
+
+func newCloseTrackingSender() *closeTrackingSender {
+	s := &closeTrackingSender{}
+	s.SetPriority(level.Info)
+	return s
+}
+
+func TestReplaceSender(t *testing.T) {
+	t.Run("ReturnsPrevious", func(t *testing.T) {
+		first, second := newCloseTrackingSender(), newCloseTrackingSender()
+		logger := NewLogger(first)
+
+		prev := logger.ReplaceSender(second)
+		check.True(t, prev == first)
+		check.True(t, logger.Sender() == second)
+		check.True(t, !first.closed.Load())
+
+		logger.Info("hello")
+		check.Equal(t, first.sent.Load(), 0)
+		check.Equal(t, second.sent.Load(), 1)
+	})
+	t.Run("Standard", func(t *testing.T) {
+		orig := Sender()
+		defer SetSender(orig)
+
+		s := newCloseTrackingSender()
+		check.True(t, ReplaceSender(s) == orig)
+		check.True(t, Named("swap.test").Sender() == send.Sender(s))
+	})
+}
+
+func TestSwapSender(t *testing.T) {
+	t.Run("ClosesPrevious", func(t *testing.T) {
+		first, second := newCloseTrackingSender(), newCloseTrackingSender()
+		logger := NewLogger(first)
+		logger.Info("one")
+
+		check.NotError(t, logger.SwapSender(t.Context(), second))
+		check.True(t, first.flushed.Load())
+		check.True(t, first.closed.Load())
+		check.True(t, !second.closed.Load())
+
+		logger.Info("two")
+		check.Equal(t, first.sent.Load(), 1)
+		check.Equal(t, second.sent.Load(), 1)
+	})
+	t.Run("SameSender", func(t *testing.T) {
+		s := newCloseTrackingSender()
+		logger := NewLogger(s)
+		check.NotError(t, logger.SwapSender(t.Context(), s))
+		check.True(t, !s.closed.Load())
+
+		logger.Info("still open")
+		check.Equal(t, s.sent.Load(), 1)
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		senders := []*closeTrackingSender{newCloseTrackingSender()}
+		logger := NewLogger(senders[0])
+
+		ctx, cancel := context.WithCancel(t.Context())
+		wg := &sync.WaitGroup{}
+		var total atomic.Int64
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					logger.Info("hello")
+					logger.InfoCtx(t.Context(), "hello")
+					total.Add(2)
+				}
+			}()
+		}
+
+		for range 50 {
+			next := newCloseTrackingSender()
+			check.NotError(t, logger.SwapSender(t.Context(), next))
+			senders = append(senders, next)
+		}
+		cancel()
+		wg.Wait()
+
+		var sent int64
+		for _, s := range senders {
+			check.Equal(t, s.late.Load(), 0)
+			sent += s.sent.Load()
+		}
+		check.Equal(t, sent, total.Load())
+	})
+	t.Run("Reentrant", func(t *testing.T) {
+		next := newCloseTrackingSender()
+		prev := &blockingSender{entered: make(chan struct{}), unblock: make(chan struct{})}
+		prev.SetPriority(level.Info)
+		logger := NewLogger(prev)
+		prev.logger = logger
+
+		done := make(chan struct{})
+		go func() { defer close(done); logger.Info("outer") }()
+		<-prev.entered
+
+		swapped := make(chan error)
+		go func() { swapped <- logger.SwapSender(t.Context(), next) }()
+		for logger.Sender() != send.Sender(next) {
+			runtime.Gosched()
+		}
+
+		// the blocked send logs back through the logger while the
+		// swap waits for it.
+		close(prev.unblock)
+		<-done
+		check.NotError(t, <-swapped)
+		check.Equal(t, next.sent.Load(), 1)
+		check.True(t, prev.closed.Load())
+	})
+	t.Run("DrainTimeout", func(t *testing.T) {
+		prev := &blockingSender{entered: make(chan struct{}), unblock: make(chan struct{})}
+		prev.SetPriority(level.Info)
+		logger := NewLogger(prev)
+
+		done := make(chan struct{})
+		go func() { defer close(done); logger.Info("stuck") }()
+		<-prev.entered
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		err := logger.SwapSender(ctx, newCloseTrackingSender())
+		check.ErrorIs(t, err, context.DeadlineExceeded)
+		check.True(t, !prev.closed.Load())
+
+		close(prev.unblock)
+		<-done
+	})
+	t.Run("Inflight", func(t *testing.T) {
+		f := newInflight()
+		check.True(t, f.enter())
+		check.True(t, f.enter())
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		check.ErrorIs(t, f.retire(ctx), context.DeadlineExceeded)
+		check.True(t, !f.enter())
+
+		f.exit()
+		f.exit()
+		check.NotError(t, f.retire(t.Context()))
+		check.NotError(t, newInflight().retire(t.Context()))
+	})
+}
+
+// blockingSender blocks in Send until unblocked, and then logs
+// through its logger, if set.
+type blockingSender struct {
+	send.Base
+	logger  Logger
+	entered chan struct{}
+	unblock chan struct{}
+	closed  atomic.Bool
+}
+
+func (s *blockingSender) Send(m message.Composer) {
+	if !send.ShouldLog(s, m) {
+		return
+	}
+	close(s.entered)
+	<-s.unblock
+	if s.logger.impl != nil {
+		s.logger.Info("nested")
+	}
+}
+
+func (s *blockingSender) Close() error { s.closed.Store(true); return nil }
@@ -6,6 +6,7 @@
 //   - MessageFormatter implementations: plain, default, JSON, callsite
 //   - Native loggers (no grip layer) for apples-to-apples comparison
 //   - Parallel throughput for the most common sender/message combinations
+//   - The Logger fast path for messages below the threshold
 //
 // Run with:
 //
@@ -25,6 +26,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/tychoish/grip"
 	"github.com/tychoish/grip/level"
 	"github.com/tychoish/grip/message"
 	"github.com/tychoish/grip/send"
@@ -761,3 +763,62 @@ func BenchmarkParallel(b *testing.B) {
 		_ = sender.Close()
 	}
 }
+
+// ── BenchmarkDropped ─────────────────────────────────────────────────────────
+
+// BenchmarkDropped measures the cost of logging messages that fall below the
+// threshold of the logger or its sender. Values that are not Composers are
+// never converted in this case, so these calls should not allocate.
+func BenchmarkDropped(b *testing.B) {
+	for _, tc := range droppedCases() {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				tc.fn()
+			}
+		})
+	}
+}
+
+// TestDroppedDoesNotAllocate enforces the property that BenchmarkDropped
+// reports.
+func TestDroppedDoesNotAllocate(t *testing.T) {
+	for _, tc := range droppedCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tc.fn); allocs != 0 {
+				t.Errorf("dropped message allocated %.1f times per call", allocs)
+			}
+		})
+	}
+}
+
+func droppedCases() []struct {
+	name string
+	fn   func()
+} {
+	sender := send.MakeWriter(io.Discard)
+	sender.SetPriority(level.Info)
+	logger := grip.NewLogger(sender)
+
+	thresholded := grip.NewLogger(discardSender())
+	thresholded.SetThreshold(level.Warning)
+
+	fielded := logger.With("key", "value")
+	ctx := context.Background()
+
+	return []struct {
+		name string
+		fn   func()
+	}{
+		{name: "Enabled", fn: func() { _ = logger.Enabled(level.Debug) }},
+		{name: "String", fn: func() { logger.Debug(shortMsg) }},
+		{name: "Error", fn: func() { logger.Debug(errBench) }},
+		{name: "Map", fn: func() { logger.Trace(benchFields) }},
+		{name: "Log", fn: func() { logger.Log(level.Debug, shortMsg) }},
+		{name: "Context", fn: func() { logger.DebugCtx(ctx, shortMsg) }},
+		{name: "LoggerThreshold", fn: func() { thresholded.Info(shortMsg) }},
+		{name: "Fields", fn: func() { fielded.Debug(shortMsg) }},
+	}
+}
+
+var benchFields = map[string]any{"user": "alice", "status": 200}
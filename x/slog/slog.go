@@ -29,7 +29,10 @@ import (
 )
 
 // MakeSender constructs a new send.Sender that wraps the provided
-// slog.Logger and uses the supplied context for all log events.
+// slog.Logger and uses the supplied context for all log events sent
+// with Send. The sender also implements send.ContextSender, and uses
+// the context of the logging call for events sent with SendContext
+// (e.g. by the grip.Logger's *Ctx methods.)
 func MakeSender(ctx context.Context, logger *slog.Logger) send.Sender {
 	s := &sender{ctx: ctx, logger: logger}
 	s.SetPriority(level.Trace) // capture all levels by default
@@ -42,9 +45,10 @@ type sender struct {
 	send.Base
 }
 
-// convertLevel maps grip's level.Priority to slog.Level.
+// convertLevel maps grip's level.Priority to slog.Level; custom
+// priorities map to the nearest standard level first.
 func convertLevel(p level.Priority) slog.Level {
-	switch {
+	switch p = level.Standard(p); {
 	case p >= level.Error:
 		return slog.LevelError
 	case p >= level.Warning:
@@ -56,20 +60,26 @@ func convertLevel(p level.Priority) slog.Level {
 	}
 }
 
-// Send implements the send.Sender interface.
+// Send implements the send.Sender interface, using the context
+// provided to MakeSender.
 //
 // The method preserves Grip semantics while forwarding records to slog:
 //   - honour sender-level filtering via send.ShouldLog
 //   - short-circuit when the slog handler has disabled the requested level
 //   - surface formatter and handler errors via HandleError / HandleErrorOK so
 //     that upstream error counters and hooks remain consistent.
-func (s *sender) Send(m message.Composer) {
+func (s *sender) Send(m message.Composer) { s.SendContext(s.ctx, m) }
+
+// SendContext implements the send.ContextSender interface, and is
+// equivalent to Send, except that the provided context is passed to
+// the slog.Handler in place of the context provided to MakeSender.
+func (s *sender) SendContext(ctx context.Context, m message.Composer) {
 	if !send.ShouldLog(s, m) {
 		return
 	}
 
 	lvl := convertLevel(m.Priority())
-	if !s.logger.Handler().Enabled(s.ctx, lvl) {
+	if !s.logger.Handler().Enabled(ctx, lvl) {
 		// Early-out: if the underlying slog.Handler is disabled for this level,
 		// skip all further processing to avoid unnecessary allocation and
 		// formatting work.
@@ -84,9 +94,9 @@ func (s *sender) Send(m message.Composer) {
 			message.GetDefaultFieldsMessage(m, ""),
 			0,
 		)
-		addAttrsFromPayload(s.ctx, &rec, m.Raw())
+		addAttrsFromPayload(ctx, &rec, m.Raw())
 
-		if err := s.logger.Handler().Handle(s.ctx, rec); err != nil {
+		if err := s.logger.Handler().Handle(ctx, rec); err != nil {
 			s.HandleError(send.WrapError(err, m))
 		}
 		return
@@ -99,7 +109,7 @@ func (s *sender) Send(m message.Composer) {
 	}
 
 	rec := slog.NewRecord(time.Now(), lvl, out, 0)
-	if err = s.logger.Handler().Handle(s.ctx, rec); err != nil {
+	if err = s.logger.Handler().Handle(ctx, rec); err != nil {
 		s.HandleError(send.WrapError(err, m))
 	}
 }
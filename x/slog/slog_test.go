@@ -8,6 +8,7 @@ import (
 
 	"github.com/tychoish/grip/level"
 	"github.com/tychoish/grip/message"
+	"github.com/tychoish/grip/send"
 	slogx "github.com/tychoish/grip/x/slog"
 )
 
@@ -262,3 +263,35 @@ func (c *countingComposer) String() string {
 	c.resolved = true
 	return "expensive"
 }
+
+type ctxKey struct{}
+
+type ctxCaptureHandler struct{ values []any }
+
+func (h *ctxCaptureHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *ctxCaptureHandler) Handle(ctx context.Context, _ slog.Record) error {
+	h.values = append(h.values, ctx.Value(ctxKey{}))
+	return nil
+}
+func (h *ctxCaptureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *ctxCaptureHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestSendContext(t *testing.T) {
+	h := &ctxCaptureHandler{}
+	sender := slogx.MakeSender(context.WithValue(t.Context(), ctxKey{}, "construction"), slog.New(h))
+
+	msg := message.MakeString("hello")
+	msg.SetPriority(level.Info)
+	sender.Send(msg)
+	send.SendContext(context.WithValue(t.Context(), ctxKey{}, "call"), sender, msg)
+
+	if len(h.values) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(h.values))
+	}
+	if h.values[0] != "construction" {
+		t.Errorf("Send used context with %v", h.values[0])
+	}
+	if h.values[1] != "call" {
+		t.Errorf("SendContext used context with %v", h.values[1])
+	}
+}
@@ -0,0 +1,16 @@
+package splunk
+
+import "github.com/tychoish/grip/send"
+
+// Importing this package makes the splunk sender available as the
+// "splunk" type for send.Build. The "url", "token", and "channel"
+// options default to the values read by GetConnectionInfo.
+func init() {
+	send.RegisterType("splunk", func(spec send.Spec, _ []send.Sender) (send.Sender, error) {
+		info := GetConnectionInfo()
+		if err := spec.DecodeOptions(&info); err != nil {
+			return nil, err
+		}
+		return MakeSender(info)
+	})
+}
@@ -0,0 +1,25 @@
+package system
+
+import (
+	"github.com/tychoish/grip"
+	"github.com/tychoish/grip/send"
+)
+
+// Importing this package makes the syslog sender available as the
+// "syslog" output for grip.Config (and the GRIP_OUTPUT environment
+// variable,) and as the "syslog" type for send.Build, which accepts
+// optional "network" and "address" options to connect to a remote
+// syslog service.
+func init() {
+	grip.RegisterOutput(grip.OutputSyslog, func(grip.Config) (send.Sender, error) { return MakeLocalSyslog(), nil })
+	send.RegisterType("syslog", func(spec send.Spec, _ []send.Sender) (send.Sender, error) {
+		var opts struct {
+			Network string `json:"network"`
+			Address string `json:"address"`
+		}
+		if err := spec.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+		return MakeSyslogSender(opts.Network, opts.Address), nil
+	})
+}
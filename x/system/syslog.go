@@ -95,7 +95,9 @@ func (s *syslogger) Send(m message.Composer) {
 }
 
 func (s *syslogger) sendToSysLog(p level.Priority, message string) error {
-	switch p {
+	// custom and unnamed priorities map to the nearest standard
+	// level.
+	switch level.Standard(p) {
 	case level.Emergency:
 		return s.logger.Emerg(message)
 	case level.Alert:
@@ -17,3 +17,9 @@ func TestLogging(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertCustomLevels(t *testing.T) {
+	assert.Equal(t, convertPrioritySystemd(level.Priority(190), 0), convertPrioritySystemd(level.Critical, 0))
+	assert.Equal(t, convertPrioritySystemd(level.Priority(75), 0), convertPrioritySystemd(level.Debug, 0))
+	assert.Equal(t, convertPrioritySystemd(level.Priority(140), 0), convertPrioritySystemd(level.Warning, 0))
+}
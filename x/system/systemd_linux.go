@@ -90,24 +90,8 @@ func convertPrioritySystemd(prio level.Priority, depth int) journal.Priority {
 	case level.Debug, level.Trace, level.Invalid:
 		return journal.PriDebug
 	default:
-		// levels increase by 25(ish); if we're going to be
-		// invalid by being too low, just return debug now,
-		// otherwise, attempt to round down to the nearest 25,
-		// should only need 1 or 2 recursions to get to some
-		// return.
-		switch {
-		case prio <= 25:
-			return journal.PriDebug
-		case prio%25 == 0 && int(prio)-25 >= 0:
-			prio -= 25
-			return convertPrioritySystemd(prio, depth+1)
-		default:
-			val := int(prio) - int(prio)%25
-			if val >= 0 {
-				return convertPrioritySystemd(level.Priority(val), depth+1)
-			}
-
-			return journal.PriDebug
-		}
+		// custom and unnamed priorities map to the nearest
+		// standard level.
+		return convertPrioritySystemd(level.Standard(prio), depth+1)
 	}
 }
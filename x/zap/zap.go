@@ -89,7 +89,7 @@ func toFields[V any](seq iter.Seq2[string, V], hint ...int) []zap.Field {
 }
 
 func convertLevel(in level.Priority) zapcore.Level {
-	switch in {
+	switch level.Standard(in) {
 	case level.Emergency:
 		return zap.ErrorLevel
 	case level.Alert:
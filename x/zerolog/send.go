@@ -28,7 +28,7 @@ func MakeSender(zl zerolog.Logger) send.Sender {
 }
 
 func convertLevel(in level.Priority) zerolog.Level {
-	switch in {
+	switch level.Standard(in) {
 	case level.Emergency:
 		return zerolog.ErrorLevel
 	case level.Alert:
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/rs/zerolog"
+	"github.com/tychoish/grip/level"
 )
 
 func TestZeroSender(t *testing.T) {
@@ -20,5 +21,17 @@ func TestZeroSender(t *testing.T) {
 		}
 
 	})
-
+	t.Run("CustomLevels", func(t *testing.T) {
+		for in, out := range map[level.Priority]zerolog.Level{
+			level.Error:         zerolog.ErrorLevel,
+			level.Priority(190): zerolog.ErrorLevel,
+			level.Priority(140): zerolog.WarnLevel,
+			level.Priority(75):  zerolog.DebugLevel,
+			level.Invalid:       zerolog.Disabled,
+		} {
+			if got := convertLevel(in); got != out {
+				t.Errorf("%s: %s != %s", in, got, out)
+			}
+		}
+	})
 }